@@ -0,0 +1,286 @@
+// Command stubs generates random data for a swagger specification, so fixtures can be generated without writing Go.
+//
+//	stubs gen --spec swagger.yaml --definition Pet --count 50
+//	stubs gen --spec swagger.yaml --operation listPets
+//	stubs gen --spec swagger.yaml --operation listPets --response 200
+//	stubs gen --spec swagger.yaml --operation listPets --parameter limit --mode maximum
+//
+// The stubs are written to stdout, or to a file per stub in the output directory.
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/go-openapi/loads"
+	"github.com/go-openapi/spec"
+	"github.com/go-openapi/stubs"
+	"go.yaml.in/yaml/v3"
+)
+
+const usage = `Usage: stubs gen --spec FILE (--definition NAME | --operation ID [--response CODE | --parameter NAME] | --parameter NAME) [flags]
+
+Generates stubs for a definition, the request or a response of an operation, or a parameter of an operation or the document.
+
+Flags:
+`
+
+func main() {
+	err := run(os.Args[1:], os.Stdout, os.Stderr)
+	switch {
+	case errors.Is(err, flag.ErrHelp):
+		return
+	case err != nil:
+		fmt.Fprintln(os.Stderr, "stubs:", err)
+		os.Exit(2)
+	}
+}
+
+// options are the flags of the gen command
+type options struct {
+	spec       string
+	definition string
+	operation  string
+	response   string
+	parameter  string
+	count      int
+	seed       int64
+	seeded     bool
+	locale     string
+	mode       string
+	format     string
+	out        string
+}
+
+// run runs the command with the arguments, the stubs are written to stdout unless there's an output directory
+func run(args []string, stdout, stderr io.Writer) error {
+	fs := flag.NewFlagSet("stubs gen", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprint(stderr, usage)
+		fs.PrintDefaults()
+	}
+	if len(args) == 0 || args[0] != "gen" {
+		fs.Usage()
+		return errors.New("expected the gen command")
+	}
+
+	var opts options
+	fs.StringVar(&opts.spec, "spec", "", "the swagger specification to load")
+	fs.StringVar(&opts.definition, "definition", "", "the name of a definition to generate")
+	fs.StringVar(&opts.operation, "operation", "", "the id of an operation to generate a request for")
+	fs.StringVar(&opts.response, "response", "", "the status code of a response of the operation to generate, or default")
+	fs.StringVar(&opts.parameter, "parameter", "", "the name of a parameter of the operation or of the document to generate")
+	fs.IntVar(&opts.count, "count", 1, "the number of stubs to generate")
+	fs.Int64Var(&opts.seed, "seed", 0, "the seed of the random data, the same seed generates the same stubs (default random)")
+	fs.StringVar(&opts.locale, "locale", "en", "the language of the generated text")
+	fs.StringVar(&opts.mode, "mode", "valid", "the validations the stubs violate separated by | (eg. maxLength|pattern), or invalid for a random validation")
+	fs.StringVar(&opts.format, "format", "json", "the output format: json, jsonl or yaml")
+	fs.StringVar(&opts.out, "out", "", "the directory to write a file per stub to (default stdout)")
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+	fs.Visit(func(f *flag.Flag) {
+		opts.seeded = opts.seeded || f.Name == "seed"
+	})
+	if fs.NArg() > 0 {
+		return fmt.Errorf("unexpected arguments %s", strings.Join(fs.Args(), " "))
+	}
+
+	name, generate, err := opts.generator()
+	if err != nil {
+		return err
+	}
+	values := make([]interface{}, 0, opts.count)
+	for i := 0; i < opts.count; i++ {
+		value, err := generate()
+		if err != nil {
+			return err
+		}
+		values = append(values, value)
+	}
+	if opts.out != "" {
+		return writeFiles(opts.out, name, opts.format, values)
+	}
+	return write(stdout, opts.format, values)
+}
+
+// generator loads the document and returns the name of the stubs and a function which generates a stub
+func (o *options) generator() (string, func() (interface{}, error), error) {
+	switch {
+	case o.spec == "":
+		return "", nil, errors.New("--spec is required")
+	case o.count < 1:
+		return "", nil, errors.New("--count must be at least 1")
+	case o.format != "json" && o.format != "jsonl" && o.format != "yaml":
+		return "", nil, fmt.Errorf("unknown format %q, expected json, jsonl or yaml", o.format)
+	case o.definition != "" && (o.operation != "" || o.parameter != ""):
+		return "", nil, errors.New("--definition can't be combined with --operation or --parameter")
+	case o.definition == "" && o.operation == "" && o.parameter == "":
+		return "", nil, errors.New("one of --definition, --operation or --parameter is required")
+	case o.response != "" && (o.operation == "" || o.parameter != ""):
+		return "", nil, errors.New("--response needs an --operation and can't be combined with --parameter")
+	}
+
+	mode, err := stubs.ParseStubMode(o.mode)
+	if err != nil {
+		return "", nil, err
+	}
+	doc, err := loads.Spec(o.spec)
+	if err != nil {
+		return "", nil, err
+	}
+	gen := stubs.NewGenerator(doc)
+	gen.Language, gen.Mode = o.locale, mode
+	if o.seeded {
+		gen.Source = rand.NewSource(o.seed)
+	}
+
+	switch {
+	case o.definition != "":
+		if _, ok := doc.Spec().Definitions[o.definition]; !ok {
+			return "", nil, fmt.Errorf("no definition found with name %s", o.definition)
+		}
+		schema := spec.RefSchema("#/definitions/" + o.definition)
+		return o.definition, func() (interface{}, error) { return gen.GenSchema(o.definition, schema) }, nil
+
+	case o.parameter != "":
+		param, err := findParameter(doc, o.operation, o.parameter)
+		if err != nil {
+			return "", nil, err
+		}
+		name := o.parameter
+		if o.operation != "" {
+			name = o.operation + "-" + o.parameter
+		}
+		return name, func() (interface{}, error) { return gen.GenParameter(o.parameter, param) }, nil
+
+	case o.response == "default":
+		return o.operation + "-response-default", func() (interface{}, error) { return gen.GenDefaultResponse(o.operation) }, nil
+
+	case o.response != "":
+		statusCode, err := strconv.Atoi(o.response)
+		if err != nil {
+			return "", nil, fmt.Errorf("invalid status code %q for --response", o.response)
+		}
+		return o.operation + "-response-" + o.response, func() (interface{}, error) { return gen.GenResponse(o.operation, statusCode) }, nil
+
+	default:
+		return o.operation + "-request", func() (interface{}, error) { return gen.GenRequest(o.operation) }, nil
+	}
+}
+
+// findParameter returns the parameter with the name of an operation, or of the document when there's no operation
+func findParameter(doc *loads.Document, operationID, name string) (*spec.Parameter, error) {
+	if operationID == "" {
+		param, ok := doc.Spec().Parameters[name]
+		if !ok {
+			return nil, fmt.Errorf("no parameter found with name %s", name)
+		}
+		return &param, nil
+	}
+
+	analyzed := doc.Analyzer
+	method, path, _, ok := analyzed.OperationForName(operationID)
+	if !ok {
+		return nil, fmt.Errorf("no operation found with id %s", operationID)
+	}
+	for _, param := range analyzed.ParamsFor(method, path) {
+		if param.Name == name {
+			return &param, nil
+		}
+	}
+	return nil, fmt.Errorf("operation %s has no parameter %s", operationID, name)
+}
+
+// write writes the stubs in a format: json is a single value or an array, jsonl has a value per line and yaml is a document
+func write(w io.Writer, format string, values []interface{}) error {
+	var value interface{} = values
+	if len(values) == 1 {
+		value = values[0]
+	}
+	switch format {
+	case "jsonl":
+		for _, v := range values {
+			data, err := json.Marshal(v)
+			if err != nil {
+				return err
+			}
+			if _, err := fmt.Fprintf(w, "%s\n", data); err != nil {
+				return err
+			}
+		}
+		return nil
+	case "yaml":
+		data, err := toYAML(value)
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(data)
+		return err
+	default:
+		data, err := json.MarshalIndent(value, "", "  ")
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintf(w, "%s\n", data)
+		return err
+	}
+}
+
+// writeFiles writes a file per stub to the directory, named after the stubs and numbered when there are several.
+// The stubs in the jsonl format are written to a single file.
+func writeFiles(dir, name, format string, values []interface{}) error {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	if format == "jsonl" || len(values) == 1 {
+		return writeFile(filepath.Join(dir, name+"."+format), format, values)
+	}
+	for i, value := range values {
+		if err := writeFile(filepath.Join(dir, fmt.Sprintf("%s-%d.%s", name, i+1, format)), format, []interface{}{value}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeFile(path, format string, values []interface{}) error {
+	var buf bytes.Buffer
+	if err := write(&buf, format, values); err != nil {
+		return err
+	}
+	return os.WriteFile(path, buf.Bytes(), 0o644)
+}
+
+// toYAML converts a value to yaml through its json representation, so values with a json encoding (eg. dates) are encoded the same way
+func toYAML(value interface{}) ([]byte, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	var node yaml.Node
+	if err := yaml.Unmarshal(data, &node); err != nil {
+		return nil, err
+	}
+	blockStyle(&node)
+	return yaml.Marshal(&node)
+}
+
+// blockStyle resets the styles of the json a node was decoded from, so the yaml is written in block style
+// and only the strings which need quotes are quoted
+func blockStyle(node *yaml.Node) {
+	node.Style = 0
+	for _, child := range node.Content {
+		blockStyle(child)
+	}
+}
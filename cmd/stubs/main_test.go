@@ -0,0 +1,163 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.yaml.in/yaml/v3"
+)
+
+const petstore = "../../fixtures/petstore.yaml"
+
+func gen(t *testing.T, args ...string) (string, error) {
+	var stdout, stderr bytes.Buffer
+	err := run(append([]string{"gen", "--spec", petstore}, args...), &stdout, &stderr)
+	return stdout.String(), err
+}
+
+func TestRun_Definition(t *testing.T) {
+	out, err := gen(t, "--definition", "Pet", "--count", "5", "--seed", "42")
+	if !assert.NoError(t, err) {
+		return
+	}
+	var pets []map[string]interface{}
+	if assert.NoError(t, json.Unmarshal([]byte(out), &pets)) && assert.Len(t, pets, 5) {
+		for _, pet := range pets {
+			assert.NotEmpty(t, pet["name"])
+		}
+	}
+
+	// the same seed generates the same stubs
+	again, err := gen(t, "--definition", "Pet", "--count", "5", "--seed", "42")
+	assert.NoError(t, err)
+	assert.Equal(t, out, again)
+
+	out, err = gen(t, "--definition", "Tag")
+	if assert.NoError(t, err) {
+		var tag map[string]interface{}
+		assert.NoError(t, json.Unmarshal([]byte(out), &tag))
+		assert.Contains(t, tag, "name")
+	}
+
+	out, err = gen(t, "--definition", "Pet", "--count", "3", "--format", "jsonl")
+	if assert.NoError(t, err) {
+		lines := strings.Split(strings.TrimSpace(out), "\n")
+		if assert.Len(t, lines, 3) {
+			var pet map[string]interface{}
+			assert.NoError(t, json.Unmarshal([]byte(lines[2]), &pet))
+		}
+	}
+
+	out, err = gen(t, "--definition", "Pet", "--format", "yaml", "--locale", "de")
+	if assert.NoError(t, err) {
+		var pet map[string]interface{}
+		assert.NoError(t, yaml.Unmarshal([]byte(out), &pet))
+		assert.NotEmpty(t, pet["name"])
+		assert.NotContains(t, out, "{")
+	}
+
+	_, err = gen(t, "--definition", "Owner")
+	assert.Error(t, err)
+}
+
+func TestRun_Operation(t *testing.T) {
+	out, err := gen(t, "--operation", "listPets", "--count", "2")
+	if assert.NoError(t, err) {
+		var requests []map[string]interface{}
+		if assert.NoError(t, json.Unmarshal([]byte(out), &requests)) && assert.Len(t, requests, 2) {
+			assert.Equal(t, "GET", requests[0]["method"])
+			assert.Equal(t, "/api/pets", requests[0]["path"])
+		}
+	}
+
+	out, err = gen(t, "--operation", "getPet", "--response", "404")
+	if assert.NoError(t, err) {
+		var resp map[string]interface{}
+		if assert.NoError(t, json.Unmarshal([]byte(out), &resp)) {
+			assert.Equal(t, float64(404), resp["statusCode"])
+			assert.Contains(t, resp["body"], "message")
+		}
+	}
+	out, err = gen(t, "--operation", "listPets", "--response", "default")
+	if assert.NoError(t, err) {
+		assert.Contains(t, out, `"default": true`)
+	}
+	_, err = gen(t, "--operation", "getPet", "--response", "default")
+	assert.Error(t, err)
+
+	// a parameter of an operation or of the document, the mode applies to the parameter
+	out, err = gen(t, "--operation", "listPets", "--parameter", "limit", "--mode", "maximum", "--count", "10", "--format", "jsonl")
+	if assert.NoError(t, err) {
+		for _, line := range strings.Split(strings.TrimSpace(out), "\n") {
+			var limit int
+			assert.NoError(t, json.Unmarshal([]byte(line), &limit))
+			assert.True(t, limit > 100, line)
+		}
+	}
+	out, err = gen(t, "--parameter", "limit")
+	if assert.NoError(t, err) {
+		var limit int
+		assert.NoError(t, json.Unmarshal([]byte(out), &limit))
+		assert.True(t, limit >= 1 && limit <= 100)
+	}
+
+	_, err = gen(t, "--operation", "missing")
+	assert.Error(t, err)
+	_, err = gen(t, "--operation", "getPet", "--response", "503")
+	assert.Error(t, err)
+	_, err = gen(t, "--operation", "getPet", "--parameter", "limit")
+	assert.Error(t, err)
+}
+
+func TestRun_Out(t *testing.T) {
+	dir := t.TempDir()
+	_, err := gen(t, "--definition", "Pet", "--count", "3", "--format", "yaml", "--out", dir)
+	if !assert.NoError(t, err) {
+		return
+	}
+	for _, name := range []string{"Pet-1.yaml", "Pet-2.yaml", "Pet-3.yaml"} {
+		data, err := os.ReadFile(filepath.Join(dir, name))
+		if assert.NoError(t, err) {
+			var pet map[string]interface{}
+			assert.NoError(t, yaml.Unmarshal(data, &pet))
+		}
+	}
+
+	_, err = gen(t, "--operation", "createPet", "--count", "4", "--format", "jsonl", "--out", dir)
+	if assert.NoError(t, err) {
+		data, err := os.ReadFile(filepath.Join(dir, "createPet-request.jsonl"))
+		assert.NoError(t, err)
+		assert.Len(t, strings.Split(strings.TrimSpace(string(data)), "\n"), 4)
+	}
+	_, err = gen(t, "--operation", "createPet", "--response", "201", "--out", dir)
+	if assert.NoError(t, err) {
+		_, err := os.Stat(filepath.Join(dir, "createPet-response-201.json"))
+		assert.NoError(t, err)
+	}
+}
+
+func TestRun_Errors(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	assert.Error(t, run(nil, &stdout, &stderr))
+	assert.Contains(t, stderr.String(), "Usage")
+	assert.Error(t, run([]string{"gen", "--definition", "Pet"}, &stdout, &stderr))
+
+	for _, args := range [][]string{
+		{},
+		{"--definition", "Pet", "--operation", "listPets"},
+		{"--definition", "Pet", "--count", "0"},
+		{"--definition", "Pet", "--format", "xml"},
+		{"--definition", "Pet", "--mode", "lenient"},
+		{"--definition", "Pet", "extra"},
+		{"--response", "200", "--definition", "Pet"},
+		{"--operation", "getPet", "--response", "ok"},
+	} {
+		_, err := gen(t, args...)
+		assert.Error(t, err, "%v", args)
+	}
+}
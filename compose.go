@@ -0,0 +1,364 @@
+package stubs
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/go-openapi/spec"
+	"github.com/go-openapi/strfmt"
+	"github.com/go-openapi/swag"
+	"github.com/go-openapi/validate"
+)
+
+// allOf merges the subschemas of a schema with allOf into a single schema,
+// so a value generated for the merged schema validates against every subschema.
+// The seen references are the schemas being merged, a schema which is composed of itself can't be merged.
+func (g *generators) allOf(resolved resolvedSchema, seen []string) (resolvedSchema, error) {
+	if resolved.ref != "" {
+		for _, ref := range seen {
+			if ref == resolved.ref {
+				return resolvedSchema{}, fmt.Errorf("circular allOf reference %s", resolved.ref)
+			}
+		}
+		seen = append(seen[:len(seen):len(seen)], resolved.ref)
+	}
+
+	merged := *resolved.schema
+	merged.AllOf = nil
+	merged.Properties = make(spec.SchemaProperties, len(resolved.schema.Properties))
+	for name, prop := range resolved.schema.Properties {
+		merged.Properties[name] = prop
+	}
+	merged.Required = append([]string(nil), resolved.schema.Required...)
+	merged.Extensions = make(spec.Extensions, len(resolved.schema.Extensions))
+	for k, v := range resolved.schema.Extensions {
+		merged.Extensions[k] = v
+	}
+	result := resolvedSchema{schema: &merged, base: resolved.base, ref: resolved.ref, patterns: resolved.patterns}
+
+	for i := range resolved.schema.AllOf {
+		branch, err := g.follow(&resolved.schema.AllOf[i], resolved.base)
+		if err != nil {
+			return resolvedSchema{}, err
+		}
+		if len(branch.schema.AllOf) > 0 {
+			if branch, err = g.allOf(branch, seen); err != nil {
+				return resolvedSchema{}, err
+			}
+		}
+		if err := g.merge(&result, branch); err != nil {
+			return resolvedSchema{}, fmt.Errorf("unable to merge allOf of %s: %v", describeSchema(resolved), err)
+		}
+	}
+	return result, nil
+}
+
+// composed is a composite generator for a schema with oneOf, anyOf or not.
+// A value for oneOf is generated for one subschema and doesn't validate against the others,
+// a value for anyOf is generated for one or more subschemas and a value for not doesn't validate against its subschema.
+// The picked subschemas are reported to the branches callback of the generator.
+func (g *generators) composed(opts GeneratorOpts) (interface{}, error) {
+	composed := opts.(composedOpts)
+	keyword, count := composed.composition()
+	for attempt := 0; attempt < maxAttempts; attempt++ {
+		var indexes []int
+		switch keyword {
+		case "oneOf":
+			indexes = []int{g.rand.Intn(count)}
+		case "anyOf":
+			indexes = g.rand.Perm(count)[:1+g.rand.Intn(count)]
+			sort.Ints(indexes)
+		}
+
+		derived, err := composed.derive(keyword, indexes)
+		if err != nil {
+			if len(indexes) > 1 {
+				// the subschemas can't be combined, another attempt picks other subschemas
+				continue
+			}
+			return nil, err
+		}
+		datagen, found := g.For(derived)
+		if !found {
+			return nil, fmt.Errorf("no generator found for %s of [%s]", keyword, opts.FieldName())
+		}
+		value, err := datagen(derived)
+		if err != nil {
+			return nil, err
+		}
+
+		exclusive, err := composed.exclusive(keyword, indexes, value)
+		if err != nil {
+			return nil, err
+		}
+		if exclusive {
+			if g.branches != nil && len(indexes) > 0 {
+				g.branches(Branch{Pointer: composed.pointer(), Keyword: keyword, Indexes: indexes})
+			}
+			return value, nil
+		}
+	}
+	return nil, fmt.Errorf("unable to generate a value for %s of [%s] after %d attempts", keyword, opts.FieldName(), maxAttempts)
+}
+
+// matches returns true when a value validates against a schema, which is defined in the document at the base
+func (g *generators) matches(schema spec.Schema, base string, value interface{}) (bool, error) {
+	result, err := g.validate(schema, base, value)
+	if err != nil {
+		return false, err
+	}
+	return result.IsValid(), nil
+}
+
+// validate validates a value against a schema, which is defined in the document at the base.
+// An empty base is the document of the generator.
+func (g *generators) validate(schema spec.Schema, base string, value interface{}) (result *validate.Result, err error) {
+	// expanding references changes the properties and items of a schema in place, so the schema is copied first
+	data, err := json.Marshal(schema)
+	if err != nil {
+		return nil, err
+	}
+	schema = spec.Schema{}
+	if err = json.Unmarshal(data, &schema); err != nil {
+		return nil, err
+	}
+
+	var root interface{}
+	if g.resolver != nil {
+		if base == "" {
+			base = g.resolver.basePath
+		}
+		if base != "" {
+			// the validator resolves references relative to the working directory,
+			// so the references are expanded relative to the document of the schema first
+			if err = spec.ExpandSchemaWithBasePath(&schema, nil, &spec.ExpandOptions{RelativeBase: base}); err != nil {
+				return nil, fmt.Errorf("unable to validate against schema: %v", err)
+			}
+		} else {
+			root = g.resolver.root
+		}
+	}
+
+	defer func() {
+		// the validator panics for a schema it can't use
+		if r := recover(); r != nil {
+			result, err = nil, fmt.Errorf("unable to validate against schema: %v", r)
+		}
+	}()
+	if result = validate.NewSchemaValidator(&schema, root, "", strfmt.Default).Validate(value); result == nil {
+		result = new(validate.Result)
+	}
+	return result, nil
+}
+
+// merge adds the properties and validations of a subschema to a merged schema,
+// the validations are combined so a value satisfies both schemas.
+func (g *generators) merge(dst *resolvedSchema, src resolvedSchema) error {
+	schema, err := g.rebase(*src.schema, src.base, dst.base)
+	if err != nil {
+		return err
+	}
+	into := dst.schema
+
+	switch {
+	case len(schema.Type) == 0:
+	case len(into.Type) == 0 || into.Type[0] == "number" && schema.Type[0] == "integer":
+		into.Type = schema.Type
+	case into.Type[0] != schema.Type[0] && !(into.Type[0] == "integer" && schema.Type[0] == "number"):
+		return fmt.Errorf("type %s can't be combined with type %s", into.Type[0], schema.Type[0])
+	}
+	if into.Format == "" {
+		into.Format = schema.Format
+	}
+
+	// numeric validations, the range is the intersection of both ranges
+	if schema.Maximum != nil && (into.Maximum == nil || *schema.Maximum < *into.Maximum || *schema.Maximum == *into.Maximum && schema.ExclusiveMaximum) {
+		into.Maximum, into.ExclusiveMaximum = schema.Maximum, schema.ExclusiveMaximum
+	}
+	if schema.Minimum != nil && (into.Minimum == nil || *schema.Minimum > *into.Minimum || *schema.Minimum == *into.Minimum && schema.ExclusiveMinimum) {
+		into.Minimum, into.ExclusiveMinimum = schema.Minimum, schema.ExclusiveMinimum
+	}
+	if schema.MultipleOf != nil {
+		if into.MultipleOf == nil {
+			into.MultipleOf = schema.MultipleOf
+		} else {
+			mo, ok := leastCommonMultiple(*into.MultipleOf, *schema.MultipleOf)
+			if !ok {
+				return fmt.Errorf("multipleOf %v can't be combined with multipleOf %v", *into.MultipleOf, *schema.MultipleOf)
+			}
+			into.MultipleOf = &mo
+		}
+	}
+
+	// string validations, a value has to match all the patterns
+	into.MaxLength = minInt64(into.MaxLength, schema.MaxLength)
+	into.MinLength = maxInt64(into.MinLength, schema.MinLength)
+	for _, pattern := range append([]string{schema.Pattern}, src.patterns...) {
+		switch {
+		case pattern == "" || pattern == into.Pattern || swag.ContainsStrings(dst.patterns, pattern):
+		case into.Pattern == "":
+			into.Pattern = pattern
+		default:
+			dst.patterns = append(dst.patterns[:len(dst.patterns):len(dst.patterns)], pattern)
+		}
+	}
+
+	// collection validations, the members have to validate against the items of both schemas
+	into.MaxItems = minInt64(into.MaxItems, schema.MaxItems)
+	into.MinItems = maxInt64(into.MinItems, schema.MinItems)
+	into.UniqueItems = into.UniqueItems || schema.UniqueItems
+	if schema.Items != nil && schema.Items.Schema != nil {
+		if into.Items == nil || into.Items.Schema == nil {
+			into.Items = schema.Items
+		} else {
+			into.Items = &spec.SchemaOrArray{Schema: &spec.Schema{SchemaProps: spec.SchemaProps{
+				AllOf: []spec.Schema{*into.Items.Schema, *schema.Items.Schema},
+			}}}
+		}
+	}
+
+	if len(schema.Enum) > 0 {
+		if len(into.Enum) == 0 {
+			into.Enum = schema.Enum
+		} else {
+			var common []interface{}
+			for _, v := range into.Enum {
+				if enumContains(schema.Enum, v) {
+					common = append(common, v)
+				}
+			}
+			if len(common) == 0 {
+				return fmt.Errorf("enum %v has no values in common with enum %v", into.Enum, schema.Enum)
+			}
+			into.Enum = common
+		}
+	}
+
+	// object validations, a property defined by both schemas has to validate against both definitions
+	for _, name := range schema.Required {
+		if !swag.ContainsStrings(into.Required, name) {
+			into.Required = append(into.Required, name)
+		}
+	}
+	for name, prop := range schema.Properties {
+		if existing, ok := into.Properties[name]; ok {
+			prop = spec.Schema{SchemaProps: spec.SchemaProps{AllOf: []spec.Schema{existing, prop}}}
+		}
+		into.Properties[name] = prop
+	}
+
+	into.ReadOnly = into.ReadOnly || schema.ReadOnly
+	if into.Discriminator == "" {
+		into.Discriminator = schema.Discriminator
+	}
+	// compositions of the subschema are generated for the merged schema
+	if len(into.OneOf) == 0 {
+		into.OneOf = schema.OneOf
+	}
+	if len(into.AnyOf) == 0 {
+		into.AnyOf = schema.AnyOf
+	}
+	if into.Not == nil {
+		into.Not = schema.Not
+	}
+	if _, ok := into.Extensions["x-datagen"]; !ok {
+		if ext, ok := schema.Extensions["x-datagen"]; ok {
+			into.AddExtension("x-datagen", ext)
+		}
+	}
+	return nil
+}
+
+// rebase makes the references in a schema relative to another document,
+// so the schema can be merged into a schema which is defined in that document
+func (g *generators) rebase(schema spec.Schema, from, to string) (spec.Schema, error) {
+	if from == to || g.resolver == nil {
+		return schema, nil
+	}
+	if schema.Ref.String() != "" {
+		ref, _, err := g.resolver.absolute(schema.Ref, from)
+		if err != nil {
+			return spec.Schema{}, err
+		}
+		schema.Ref = ref
+		return schema, nil
+	}
+
+	if schema.Items != nil && schema.Items.Schema != nil {
+		items, err := g.rebase(*schema.Items.Schema, from, to)
+		if err != nil {
+			return spec.Schema{}, err
+		}
+		schema.Items = &spec.SchemaOrArray{Schema: &items}
+	}
+	if len(schema.Properties) > 0 {
+		props := make(spec.SchemaProperties, len(schema.Properties))
+		for name, prop := range schema.Properties {
+			rebased, err := g.rebase(prop, from, to)
+			if err != nil {
+				return spec.Schema{}, err
+			}
+			props[name] = rebased
+		}
+		schema.Properties = props
+	}
+	if len(schema.AllOf) > 0 {
+		allOf := make([]spec.Schema, len(schema.AllOf))
+		for i, branch := range schema.AllOf {
+			rebased, err := g.rebase(branch, from, to)
+			if err != nil {
+				return spec.Schema{}, err
+			}
+			allOf[i] = rebased
+		}
+		schema.AllOf = allOf
+	}
+	return schema, nil
+}
+
+// describeSchema names a schema for error messages
+func describeSchema(resolved resolvedSchema) string {
+	if resolved.ref != "" {
+		return resolved.ref
+	}
+	return "schema"
+}
+
+// leastCommonMultiple returns the smallest positive number which is a multiple of both factors,
+// fractional factors are scaled to integers first
+func leastCommonMultiple(a, b float64) (float64, bool) {
+	if isMultipleOf(a, b) {
+		return a, true
+	}
+	if isMultipleOf(b, a) {
+		return b, true
+	}
+	for scale := 1.0; scale <= 1e9; scale *= 10 {
+		x, y := math.Round(a*scale), math.Round(b*scale)
+		if math.Abs(a*scale-x) > 1e-9 || math.Abs(b*scale-y) > 1e-9 {
+			continue
+		}
+		m, n := int64(x), int64(y)
+		for n != 0 {
+			m, n = n, m%n
+		}
+		return roundTo(x/float64(m)*y/scale, 1/scale), true
+	}
+	return 0, false
+}
+
+func minInt64(a, b *int64) *int64 {
+	if a == nil || b != nil && *b < *a {
+		return b
+	}
+	return a
+}
+
+func maxInt64(a, b *int64) *int64 {
+	if a == nil || b != nil && *b > *a {
+		return b
+	}
+	return a
+}
@@ -0,0 +1,228 @@
+package stubs
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/go-openapi/spec"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGenerator_GenSchemaAllOf(t *testing.T) {
+	doc := &spec.Swagger{SwaggerProps: spec.SwaggerProps{Definitions: spec.Definitions{
+		"Animal": *new(spec.Schema).Typed("object", "").
+			WithRequired("name").
+			SetProperty("name", *spec.StringProperty().WithMaxLength(20)).
+			SetProperty("age", *spec.Int32Property().WithMinimum(0, false).WithMaximum(30, false)),
+		"Dog": spec.Schema{SchemaProps: spec.SchemaProps{AllOf: []spec.Schema{
+			*spec.RefSchema("#/definitions/Animal"),
+			*new(spec.Schema).
+				WithRequired("age", "breed").
+				SetProperty("breed", *spec.StringProperty().WithEnum("beagle", "poodle")).
+				SetProperty("name", *spec.StringProperty().WithMinLength(3)).
+				SetProperty("age", *spec.Int32Property().WithMaximum(15, true)),
+		}}},
+	}}}
+	gen := &Generator{Spec: doc}
+
+	for i := 0; i < 20; i++ {
+		res, err := gen.GenSchema("dog", spec.RefSchema("#/definitions/Dog"))
+		if assert.NoError(t, err) {
+			dog := res.(map[string]interface{})
+			assert.Contains(t, []interface{}{"beagle", "poodle"}, dog["breed"])
+			assert.True(t, len(dog["name"].(string)) >= 3 && len(dog["name"].(string)) <= 20)
+			assert.True(t, dog["age"].(int32) >= 0 && dog["age"].(int32) < 15)
+		}
+	}
+
+	gen.Modes = map[string]StubMode{"/age": InvalidMaximum}
+	res, err := gen.GenSchema("dog", spec.RefSchema("#/definitions/Dog"))
+	if assert.NoError(t, err) {
+		assert.True(t, res.(map[string]interface{})["age"].(int32) >= 15)
+	}
+}
+
+func TestGenerator_GenSchemaAllOfValidations(t *testing.T) {
+	gen := new(Generator)
+
+	number := &spec.Schema{SchemaProps: spec.SchemaProps{AllOf: []spec.Schema{
+		*spec.Int64Property().WithMinimum(0, false).WithMaximum(100, false),
+		*new(spec.Schema).WithMaximum(50, false).WithMultipleOf(3),
+		*new(spec.Schema).WithMultipleOf(2),
+	}}}
+	for i := 0; i < 20; i++ {
+		res, err := gen.GenSchema("number", number)
+		if assert.NoError(t, err) {
+			value := res.(int64)
+			assert.True(t, value >= 0 && value <= 50)
+			assert.Zero(t, value%6)
+		}
+	}
+
+	str := &spec.Schema{SchemaProps: spec.SchemaProps{AllOf: []spec.Schema{
+		*spec.StringProperty().WithPattern("^[a-z]{5}$"),
+		*spec.StringProperty().WithPattern("[aeiou]"),
+	}}}
+	for i := 0; i < 20; i++ {
+		res, err := gen.GenSchema("word", str)
+		if assert.NoError(t, err) {
+			assert.Regexp(t, regexp.MustCompile("^[a-z]{5}$"), res)
+			assert.Regexp(t, regexp.MustCompile("[aeiou]"), res)
+		}
+	}
+
+	_, err := gen.GenSchema("conflict", &spec.Schema{SchemaProps: spec.SchemaProps{AllOf: []spec.Schema{
+		*spec.StringProperty(),
+		*spec.Int64Property(),
+	}}})
+	assert.Error(t, err)
+
+	for _, factors := range [][3]float64{{0.1, 0.3, 0.3}, {0.2, 0.3, 0.6}, {4, 6, 12}, {2.5, 1, 5}} {
+		mo, ok := leastCommonMultiple(factors[0], factors[1])
+		assert.True(t, ok)
+		assert.Equal(t, factors[2], mo)
+	}
+}
+
+func TestGenerator_GenSchemaDiscriminator(t *testing.T) {
+	pet := new(spec.Schema).Typed("object", "").
+		WithRequired("name", "petType").
+		SetProperty("name", *spec.StringProperty()).
+		SetProperty("petType", *spec.StringProperty())
+	pet.Discriminator = "petType"
+	doc := &spec.Swagger{SwaggerProps: spec.SwaggerProps{Definitions: spec.Definitions{
+		"Pet": *pet,
+		"Dog": spec.Schema{SchemaProps: spec.SchemaProps{AllOf: []spec.Schema{
+			*spec.RefSchema("#/definitions/Pet"),
+			*new(spec.Schema).WithRequired("packSize").SetProperty("packSize", *spec.Int32Property().WithMinimum(1, false)),
+		}}},
+		"Cat": spec.Schema{SchemaProps: spec.SchemaProps{AllOf: []spec.Schema{
+			*spec.RefSchema("#/definitions/Pet"),
+			*new(spec.Schema).WithRequired("huntingSkill").SetProperty("huntingSkill", *spec.StringProperty().WithEnum("lazy", "aggressive")),
+		}}},
+		"Lion": spec.Schema{SchemaProps: spec.SchemaProps{AllOf: []spec.Schema{
+			*spec.RefSchema("#/definitions/Cat"),
+			*new(spec.Schema).WithRequired("mane").SetProperty("mane", *spec.BoolProperty()),
+		}}},
+	}}}
+	gen := &Generator{Spec: doc}
+
+	types := make(map[string]bool)
+	for i := 0; i < 50; i++ {
+		res, err := gen.GenSchema("pet", spec.RefSchema("#/definitions/Pet"))
+		if !assert.NoError(t, err) {
+			continue
+		}
+		value := res.(map[string]interface{})
+		types[value["petType"].(string)] = true
+		assert.NotEmpty(t, value["name"])
+		switch value["petType"] {
+		case "Dog":
+			assert.True(t, value["packSize"].(int32) >= 1)
+		case "Lion":
+			assert.IsType(t, true, value["mane"])
+			fallthrough
+		case "Cat":
+			assert.Contains(t, []interface{}{"lazy", "aggressive"}, value["huntingSkill"])
+		default:
+			t.Errorf("unexpected type %v", value["petType"])
+		}
+	}
+	assert.Equal(t, map[string]bool{"Dog": true, "Cat": true, "Lion": true}, types)
+
+	named := spec.RefSchema("#/definitions/Pet")
+	named.AddExtension("x-datagen", map[string]interface{}{"args": []interface{}{"Lion"}})
+	res, err := gen.GenSchema("pet", named)
+	if assert.NoError(t, err) {
+		assert.Equal(t, "Lion", res.(map[string]interface{})["petType"])
+		assert.Contains(t, res, "mane")
+	}
+
+	named.AddExtension("x-datagen", map[string]interface{}{"args": []interface{}{"Cow"}})
+	_, err = gen.GenSchema("pet", named)
+	assert.Error(t, err)
+}
+
+func TestGenerator_GenSchemaOneOf(t *testing.T) {
+	cat := new(spec.Schema).Typed("object", "").
+		WithRequired("meow").
+		SetProperty("meow", *spec.BoolProperty())
+	dog := new(spec.Schema).Typed("object", "").
+		WithRequired("bark").
+		SetProperty("bark", *spec.BoolProperty())
+	dog.AdditionalProperties = &spec.SchemaOrBool{Allows: false}
+	cat.AdditionalProperties = &spec.SchemaOrBool{Allows: false}
+	pet := &spec.Schema{SchemaProps: spec.SchemaProps{OneOf: []spec.Schema{*cat, *dog}}}
+
+	var branches []Branch
+	gen := &Generator{Branches: func(branch Branch) { branches = append(branches, branch) }}
+	for i := 0; i < 20; i++ {
+		res, err := gen.GenSchema("pet", pet)
+		if assert.NoError(t, err) && assert.Len(t, branches, i+1) {
+			value := res.(map[string]interface{})
+			branch := branches[i]
+			assert.Equal(t, "oneOf", branch.Keyword)
+			assert.Equal(t, "", branch.Pointer)
+			if branch.Indexes[0] == 0 {
+				assert.Contains(t, value, "meow")
+				assert.NotContains(t, value, "bark")
+			} else {
+				assert.Contains(t, value, "bark")
+				assert.NotContains(t, value, "meow")
+			}
+		}
+	}
+
+	// both subschemas accept a number between 0 and 10, the generated number validates against exactly one
+	ranges := &spec.Schema{SchemaProps: spec.SchemaProps{OneOf: []spec.Schema{
+		*spec.Int64Property().WithMinimum(0, false).WithMaximum(20, false),
+		*spec.Int64Property().WithMinimum(-10, false).WithMaximum(10, false),
+	}}}
+	for i := 0; i < 20; i++ {
+		res, err := gen.GenSchema("number", ranges)
+		if assert.NoError(t, err) {
+			value := res.(int64)
+			assert.True(t, value < 0 || value > 10, "%d validates against both subschemas", value)
+		}
+	}
+}
+
+func TestGenerator_GenSchemaAnyOfNot(t *testing.T) {
+	obj := new(spec.Schema).Typed("object", "").
+		SetProperty("nested", spec.Schema{SchemaProps: spec.SchemaProps{AnyOf: []spec.Schema{
+			*new(spec.Schema).Typed("object", "").WithRequired("a").SetProperty("a", *spec.StringProperty()),
+			*new(spec.Schema).Typed("object", "").WithRequired("b").SetProperty("b", *spec.StringProperty()),
+		}}}).
+		WithRequired("nested")
+
+	picked := make(map[int]bool)
+	gen := &Generator{Branches: func(branch Branch) {
+		assert.Equal(t, "/nested", branch.Pointer)
+		assert.Equal(t, "anyOf", branch.Keyword)
+		for _, i := range branch.Indexes {
+			picked[i] = true
+		}
+	}}
+	for i := 0; i < 20; i++ {
+		res, err := gen.GenSchema("obj", obj)
+		if assert.NoError(t, err) {
+			nested := res.(map[string]interface{})["nested"].(map[string]interface{})
+			assert.True(t, nested["a"] != nil || nested["b"] != nil)
+		}
+	}
+	assert.Len(t, picked, 2)
+
+	not := spec.Int64Property().WithMinimum(0, false).WithMaximum(10, false)
+	not.Not = new(spec.Schema).WithMinimum(1, false).WithMaximum(9, false)
+	for i := 0; i < 20; i++ {
+		res, err := gen.GenSchema("number", not)
+		if assert.NoError(t, err) {
+			assert.Contains(t, []int64{0, 10}, res)
+		}
+	}
+
+	impossible := spec.StringProperty()
+	impossible.Not = spec.StringProperty()
+	_, err := gen.GenSchema("impossible", impossible)
+	assert.Error(t, err)
+}
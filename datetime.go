@@ -0,0 +1,203 @@
+package stubs
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-openapi/strfmt"
+	"github.com/go-openapi/swag"
+)
+
+const (
+	day  = 24 * time.Hour
+	year = 365 * day
+
+	// typedArg is the arg to get strfmt values (eg. strfmt.DateTime) instead of strings from the time generators
+	typedArg = "strfmt"
+)
+
+// timeRange is a range of time relative to the current time
+type timeRange struct {
+	from, to time.Duration
+	format   string
+}
+
+// timeHints are the ranges of time for fields which are inferred from their name
+var timeHints = map[string]timeRange{
+	"birthday":   {from: -90 * year, to: -18 * year, format: "date"},
+	"created-at": {from: -year, to: 0, format: "date-time"},
+	"expires":    {from: 0, to: year, format: "date-time"},
+}
+
+// defaultTimeRange is the range of time for dates and date-times without hints or args
+var defaultTimeRange = timeRange{from: -year, to: year}
+
+var timeUnits = map[string]time.Duration{
+	"second": time.Second,
+	"minute": time.Minute,
+	"hour":   time.Hour,
+	"day":    day,
+	"week":   7 * day,
+	"month":  30 * day,
+	"year":   year,
+}
+
+// timestamp generates a date or date-time, within the range for the field name when the field name is a hint
+func (g *generators) timestamp(format string) ValueGenerator {
+	return func(opts GeneratorOpts) (interface{}, error) {
+		rng := defaultTimeRange
+		if hint, ok := timeHints[normalizeGeneratorName(swag.ToCommandName(opts.FieldName()))]; ok {
+			rng = hint
+		}
+		return g.randomTime(opts, format, rng)
+	}
+}
+
+// timeHint generates a date or date-time within the range of the hint,
+// in the format of the options when it asks for a date or date-time.
+func (g *generators) timeHint(name string) ValueGenerator {
+	hint := timeHints[name]
+	return func(opts GeneratorOpts) (interface{}, error) {
+		format := hint.format
+		if opts.Format() == "date" || opts.Format() == "date-time" {
+			format = opts.Format()
+		}
+		return g.randomTime(opts, format, hint)
+	}
+}
+
+func (g *generators) randomTime(opts GeneratorOpts, format string, rng timeRange) (interface{}, error) {
+	lo, hi, typed, err := timeArgs(opts, g.now(), rng)
+	if err != nil {
+		return nil, err
+	}
+	value := lo.Add(time.Duration(g.randomInt64(0, int64(hi.Sub(lo)))))
+
+	switch {
+	case format == "date" && typed:
+		return strfmt.Date(value), nil
+	case format == "date":
+		return strfmt.Date(value).String(), nil
+	case typed:
+		return strfmt.DateTime(value.UTC()), nil
+	default:
+		return strfmt.DateTime(value.UTC()).String(), nil
+	}
+}
+
+// duration generates a duration, between a minute and 30 days unless the args specify a range
+func (g *generators) duration(opts GeneratorOpts) (interface{}, error) {
+	lo, hi, typed, err := durationArgs(opts, time.Minute, 30*day)
+	if err != nil {
+		return nil, err
+	}
+	value := strfmt.Duration(lo + time.Duration(g.randomInt64(0, int64(hi-lo))))
+	if typed {
+		return value, nil
+	}
+	return value.String(), nil
+}
+
+// timeArgs returns the range of time for the args of the options, a range is either
+// "past 30 days", "next 2 weeks", "between 2020-01-01 and 2020-12-31" or the two times as separate args.
+// It also returns true when the args ask for strfmt values.
+func timeArgs(opts GeneratorOpts, now time.Time, rng timeRange) (time.Time, time.Time, bool, error) {
+	lo, hi := now.Add(rng.from), now.Add(rng.to)
+	args, typed := rangeArgs(opts)
+	if len(args) == 0 {
+		return lo, hi, typed, nil
+	}
+	invalid := fmt.Errorf("invalid time range %q for [%s], expected eg. \"past 30 days\", \"next 2 weeks\" or \"between 2020-01-01 and 2020-12-31\"", strings.Join(args, " "), opts.FieldName())
+
+	fields := strings.Fields(strings.Join(args, " "))
+	keyword := strings.ToLower(fields[0])
+	switch {
+	case len(fields) == 3 && (keyword == "past" || keyword == "last" || keyword == "next" || keyword == "future"):
+		n, err := strconv.Atoi(fields[1])
+		unit, ok := timeUnits[strings.TrimSuffix(strings.ToLower(fields[2]), "s")]
+		if err != nil || !ok || n < 0 {
+			return lo, hi, typed, invalid
+		}
+		if keyword == "past" || keyword == "last" {
+			return now.Add(-time.Duration(n) * unit), now, typed, nil
+		}
+		return now, now.Add(time.Duration(n) * unit), typed, nil
+	case len(fields) == 4 && keyword == "between" && strings.EqualFold(fields[2], "and"):
+		fields = []string{fields[1], fields[3]}
+	case len(fields) != 2:
+		return lo, hi, typed, invalid
+	}
+
+	from, err := parseTime(fields[0])
+	if err != nil {
+		return lo, hi, typed, invalid
+	}
+	to, err := parseTime(fields[1])
+	if err != nil || to.Before(from) {
+		return lo, hi, typed, invalid
+	}
+	return from, to, typed, nil
+}
+
+// durationArgs returns the range of durations for the args of the options,
+// a range is either "between 1h and 2h" or the two durations as separate args.
+// It also returns true when the args ask for strfmt values.
+func durationArgs(opts GeneratorOpts, lo, hi time.Duration) (time.Duration, time.Duration, bool, error) {
+	args, typed := rangeArgs(opts)
+	if len(args) == 0 {
+		return lo, hi, typed, nil
+	}
+	invalid := fmt.Errorf("invalid duration range %q for [%s], expected eg. \"between 1h and 2h\"", strings.Join(args, " "), opts.FieldName())
+
+	fields := args
+	if len(args) == 1 {
+		fields = strings.Fields(args[0])
+		if len(fields) != 4 || !strings.EqualFold(fields[0], "between") || !strings.EqualFold(fields[2], "and") {
+			return lo, hi, typed, invalid
+		}
+		fields = []string{fields[1], fields[3]}
+	}
+	if len(fields) != 2 {
+		return lo, hi, typed, invalid
+	}
+	from, err := strfmt.ParseDuration(fields[0])
+	if err != nil {
+		return lo, hi, typed, invalid
+	}
+	to, err := strfmt.ParseDuration(fields[1])
+	if err != nil || to < from {
+		return lo, hi, typed, invalid
+	}
+	return from, to, typed, nil
+}
+
+// rangeArgs returns the args of the options as strings, without the arg which asks for strfmt values
+func rangeArgs(opts GeneratorOpts) ([]string, bool) {
+	var args []string
+	var typed bool
+	for _, arg := range opts.Args() {
+		var str string
+		switch v := arg.(type) {
+		case time.Time:
+			// yaml decodes unquoted dates as time
+			str = v.Format(time.RFC3339Nano)
+		default:
+			str = strings.TrimSpace(fmt.Sprint(v))
+		}
+		if strings.EqualFold(str, typedArg) {
+			typed = true
+			continue
+		}
+		args = append(args, str)
+	}
+	return args, typed
+}
+
+func parseTime(str string) (time.Time, error) {
+	if dt, err := strfmt.ParseDateTime(str); err == nil {
+		return time.Time(dt), nil
+	}
+	return time.Parse(strfmt.RFC3339FullDate, str)
+}
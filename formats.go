@@ -0,0 +1,155 @@
+package stubs
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-openapi/strfmt"
+)
+
+const (
+	uuid7Pattern        = "^[0-9a-f]{8}-[0-9a-f]{4}-7[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$"
+	bsonObjectIDPattern = "^[0-9a-f]{24}$"
+	// the first character of a ulid is at most 7, so its 130 bits fit in 128 bits
+	ulidPattern     = "^[0-7][0-9A-HJKMNP-TV-Z]{25}$"
+	passwordPattern = "^[A-Za-z0-9!@#$%^&*_+=-]{12,24}$"
+	// ssnPattern is the dashed form of a social security number, strfmt only accepts ssns of 11 characters
+	ssnPattern = "^[0-9]{3}-[0-9]{2}-[0-9]{4}$"
+)
+
+// formatAliases are the generators for strfmt formats which don't have the name of their generator,
+// they only apply to the format because the name means something else for a field name (eg. a country field is a country name)
+var formatAliases = map[string]string{
+	"country": "country-code",
+}
+
+// countryCodes are ISO 3166-1 alpha-2 country codes
+var countryCodes = []string{
+	"AR", "AT", "AU", "BE", "BR", "CA", "CH", "CL", "CN", "CO", "CZ", "DE", "DK", "EG", "ES", "FI", "FR", "GB", "GR", "HU",
+	"ID", "IE", "IL", "IN", "IT", "JP", "KE", "KR", "MA", "MX", "NG", "NL", "NO", "NZ", "PE", "PH", "PL", "PT", "RO", "SE",
+	"SG", "TH", "TR", "UA", "US", "VN", "ZA",
+}
+
+// currencyCodes are ISO 4217 currency codes
+var currencyCodes = []string{
+	"AUD", "BRL", "CAD", "CHF", "CNY", "CZK", "DKK", "EUR", "GBP", "HKD", "HUF", "IDR", "ILS", "INR", "JPY", "KRW",
+	"MXN", "NOK", "NZD", "PHP", "PLN", "SEK", "SGD", "THB", "TRY", "USD", "ZAR",
+}
+
+// creditCardPrefixes are the prefixes of the card numbers of the major networks, with the length of their card numbers
+var creditCardPrefixes = []struct {
+	prefix string
+	length int
+}{
+	{"4", 16},  // visa
+	{"51", 16}, // mastercard
+	{"55", 16},
+	{"34", 15}, // american express
+	{"37", 15},
+	{"6011", 16}, // discover
+}
+
+// formatGenerator returns the name of the generator for a format
+func formatGenerator(format string) string {
+	if name, ok := formatAliases[strings.ToLower(format)]; ok {
+		return name
+	}
+	return format
+}
+
+func (g *generators) pick(values []string) func() string {
+	return func() string {
+		return values[g.rand.Intn(len(values))]
+	}
+}
+
+func (g *generators) digits(n int) string {
+	var sb strings.Builder
+	for i := 0; i < n; i++ {
+		sb.WriteByte(byte('0' + g.rand.Intn(10)))
+	}
+	return sb.String()
+}
+
+// uri generates an absolute http url
+func (g *generators) uri() string {
+	return fmt.Sprintf("https://%s/%s", g.faker.DomainName(), strings.ToLower(g.faker.Words(1, false)[0]))
+}
+
+// cidr generates an ipv4 network in CIDR notation
+func (g *generators) cidr() string {
+	return fmt.Sprintf("%s/%d", g.ipv4(), 8+g.rand.Intn(25))
+}
+
+// base64 generates the base64 encoding of up to 32 random bytes
+func (g *generators) base64() string {
+	data := make([]byte, 1+g.rand.Intn(32))
+	g.rand.Read(data)
+	return base64.StdEncoding.EncodeToString(data)
+}
+
+// creditCard generates a card number of one of the major networks, with a valid check digit
+func (g *generators) creditCard() string {
+	card := creditCardPrefixes[g.rand.Intn(len(creditCardPrefixes))]
+	number := card.prefix + g.digits(card.length-len(card.prefix)-1)
+
+	// luhn check digit, every second digit from the right of the check digit is doubled
+	var sum int
+	for i := len(number) - 1; i >= 0; i-- {
+		d := int(number[i] - '0')
+		if (len(number)-i)%2 == 1 {
+			if d *= 2; d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+	}
+	return number + strconv.Itoa((10-sum%10)%10)
+}
+
+// isbn10 generates an ISBN-10 with a valid check digit
+func (g *generators) isbn10() string {
+	number := g.digits(9)
+	var sum int
+	for i := range number {
+		sum += int(number[i]-'0') * (10 - i)
+	}
+	check := (11 - sum%11) % 11
+	if check == 10 {
+		return number + "X"
+	}
+	return number + strconv.Itoa(check)
+}
+
+// isbn13 generates an ISBN-13 with a valid check digit
+func (g *generators) isbn13() string {
+	number := "978" + g.digits(9)
+	var sum int
+	for i := range number {
+		weight := 1
+		if i%2 == 1 {
+			weight = 3
+		}
+		sum += int(number[i]-'0') * weight
+	}
+	return number + strconv.Itoa((10-sum%10)%10)
+}
+
+// isoDuration generates an ISO 8601 duration in hours and minutes, between a minute and 30 days unless the args specify a range
+func (g *generators) isoDuration(opts GeneratorOpts) (interface{}, error) {
+	lo, hi, typed, err := durationArgs(opts, time.Minute, 30*day)
+	if err != nil {
+		return nil, err
+	}
+	value := lo + time.Duration(g.randomInt64(0, int64(hi-lo)))
+	if rounded := value.Round(time.Minute); rounded >= lo && rounded <= hi && rounded > 0 {
+		value = rounded
+	}
+	if typed {
+		return strfmt.DurationISO8601(value), nil
+	}
+	return strfmt.DurationISO8601(value).String(), nil
+}
@@ -2,7 +2,13 @@ package stubs
 
 import (
 	"fmt"
+	"math/rand"
+	"strings"
+	"sync"
+	"time"
 
+	"github.com/go-openapi/analysis"
+	"github.com/go-openapi/loads"
 	"github.com/go-openapi/spec"
 )
 
@@ -44,10 +50,194 @@ const (
 	Valid StubMode = 0
 )
 
+// validationModes are the modes for the individual validations
+var validationModes = []StubMode{
+	InvalidRequired,
+	InvalidMaximum,
+	InvalidMinimum,
+	InvalidMaxLength,
+	InvalidMinLength,
+	InvalidPattern,
+	InvalidMaxItems,
+	InvalidMinItems,
+	InvalidUniqueItems,
+	InvalidMultipleOf,
+	InvalidEnum,
+}
+
+var modeNames = map[StubMode]string{
+	Invalid:            "invalid",
+	InvalidRequired:    "required",
+	InvalidMaximum:     "maximum",
+	InvalidMinimum:     "minimum",
+	InvalidMaxLength:   "maxLength",
+	InvalidMinLength:   "minLength",
+	InvalidPattern:     "pattern",
+	InvalidMaxItems:    "maxItems",
+	InvalidMinItems:    "minItems",
+	InvalidUniqueItems: "uniqueItems",
+	InvalidMultipleOf:  "multipleOf",
+	InvalidEnum:        "enum",
+}
+
+// String returns the names of the validations configured in this mode
+func (s StubMode) String() string {
+	if s == Valid {
+		return "valid"
+	}
+	var names []string
+	for _, m := range append([]StubMode{Invalid}, validationModes...) {
+		if s.Has(m) {
+			names = append(names, modeNames[m])
+		}
+	}
+	return strings.Join(names, "|")
+}
+
+// ParseStubMode parses the names of validations separated by | (eg. maxLength|pattern) into a stub mode,
+// the names are the ones String returns and the names aren't case sensitive
+func ParseStubMode(text string) (StubMode, error) {
+	if strings.EqualFold(strings.TrimSpace(text), "valid") {
+		return Valid, nil
+	}
+
+	var mode StubMode
+	for _, name := range strings.Split(text, "|") {
+		name = strings.TrimSpace(name)
+		var found bool
+		for m, n := range modeNames {
+			if strings.EqualFold(n, name) {
+				mode, found = mode|m, true
+				break
+			}
+		}
+		if !found {
+			return Valid, fmt.Errorf("unknown stub mode %q", name)
+		}
+	}
+	return mode, nil
+}
+
 // Generator generates a stub for a descriptor.
 // A descriptor can either be a parameter, response header or json schema
 type Generator struct {
 	Language string
+
+	// Mode for the generated value, the zero value generates valid data
+	Mode StubMode
+
+	// Modes for locations inside the generated value, keyed by json pointer (eg. /owner/email or /tags/0).
+	// The modes only apply to the value at the location, the rest of the value is generated for Mode.
+	Modes map[string]StubMode
+
+	// Source for all the random data, the same source and descriptors produce the same stubs.
+	// Use rand.NewSource(seed) for reproducible stubs, when nil the random data is seeded from the current time.
+	// A source is not safe for concurrent use, so a generator with a source can't generate stubs concurrently.
+	Source rand.Source
+
+	// Registry of value generators for this generator only,
+	// these take precedence over the value generators in the DefaultRegistry and the built-in value generators.
+	Registry *Registry
+
+	// Spec is the document references in descriptors are resolved in
+	Spec *spec.Swagger
+
+	// BasePath is the location of the document, references to other files are relative to this location
+	BasePath string
+
+	// MaxDepth is the number of times a schema can be nested in itself, so recursive models produce a finite stub.
+	// Optional values stop the recursion by being omitted or empty, when 0 the recursion stops at a depth of 3.
+	MaxDepth int
+
+	// Clock returns the current time, which relative ranges of dates (eg. past 30 days) are based on.
+	// Use a fixed time for reproducible stubs, when nil the current time is used.
+	Clock func() time.Time
+
+	// Verify validates every generated value against its descriptor with go-openapi/validate.
+	// A valid value has to pass validation and an invalid value has to fail it with the error code of a validation selected by the modes,
+	// a value which doesn't is generated again.
+	Verify bool
+
+	// VerifyAttempts is the number of times a value is generated before verification gives up, when 0 a value is generated up to 10 times
+	VerifyAttempts int
+
+	// Branches is called for every value generated for a schema with oneOf or anyOf,
+	// with the subschemas picked for the value so tests can assert on them
+	Branches func(Branch)
+
+	lock     sync.Mutex
+	resolved *resolver
+	// analyzed is the analysis of the document, for the operations of analyzedSpec
+	analyzed     *analysis.Spec
+	analyzedSpec *spec.Swagger
+}
+
+// Branch are the subschemas of a oneOf or anyOf schema which were picked to generate a value
+type Branch struct {
+	// Pointer is the location of the value as json pointer
+	Pointer string
+	// Keyword is either oneOf or anyOf
+	Keyword string
+	// Indexes of the picked subschemas, in ascending order
+	Indexes []int
+}
+
+// NewGenerator creates a generator for the descriptors in a document.
+// References in the descriptors are resolved in the document, resolved schemas are cached by the generator.
+func NewGenerator(doc *loads.Document) *Generator {
+	return &Generator{
+		Spec:     doc.Spec(),
+		BasePath: doc.SpecFilePath(),
+	}
+}
+
+func (s *Generator) generators() (*generators, error) {
+	generator, err := newGenerator(s.Language, s.Source, s.Registry, DefaultRegistry)
+	if err != nil {
+		return nil, err
+	}
+	generator.resolver = s.resolver()
+	generator.branches = s.Branches
+	if s.Clock != nil {
+		generator.now = s.Clock
+	}
+	if s.MaxDepth > 0 {
+		generator.maxDepth = s.MaxDepth
+	}
+	return generator, nil
+}
+
+// resolver returns the resolver for the document of the generator,
+// the resolver is reused as long as the document doesn't change
+func (s *Generator) resolver() *resolver {
+	if s.Spec == nil {
+		return nil
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.resolved == nil || s.resolved.root != s.Spec || s.resolved.origin != s.BasePath {
+		s.resolved = newResolver(s.Spec, s.BasePath)
+	}
+	return s.resolved
+}
+
+// Names returns the names of all the value generators available to this generator, in alphabetical order
+func (s *Generator) Names() ([]string, error) {
+	generator, err := s.generators()
+	if err != nil {
+		return nil, err
+	}
+	return generator.names(), nil
+}
+
+// targets returns the stub modes by location, the root of the value is located at the empty pointer
+func (s *Generator) targets() modeTargets {
+	targets := make(modeTargets, len(s.Modes)+1)
+	for k, v := range s.Modes {
+		targets[k] = v
+	}
+	targets[""] |= s.Mode
+	return targets
 }
 
 // Generate a stub into the opts.Target
@@ -73,60 +263,93 @@ func (s *Generator) Generate(key string, descriptor interface{}) (interface{}, e
 
 // GenParameter generates a random value for a parameter
 func (s *Generator) GenParameter(key string, param *spec.Parameter) (interface{}, error) {
-	generator, err := newGenerator(s.Language)
-	if err != nil {
-		return nil, err
+	if param.In == "body" && param.Schema != nil {
+		if key == "" {
+			key = param.Name
+		}
+		return s.GenSchema(key, param.Schema)
 	}
 
-	gopts, err := paramGenOpts(key, param)
+	generator, err := s.generators()
 	if err != nil {
 		return nil, err
 	}
-
-	datagen, found := generator.For(gopts)
-	if !found {
-		return nil, fmt.Errorf("no generator found for parameter [%s]", param.Name)
+	targets := s.targets()
+	gopts, err := generator.paramOpts(key, param, "", targets)
+	if err != nil {
+		return nil, err
 	}
-
-	return datagen(gopts)
+	return s.generate(generator, gopts, "parameter ["+param.Name+"]", targets, checkParam(param))
 }
 
 // GenHeader generates a random value for a header
 func (s *Generator) GenHeader(key string, header *spec.Header) (interface{}, error) {
-	generator, err := newGenerator(s.Language)
+	generator, err := s.generators()
 	if err != nil {
 		return nil, err
 	}
 
-	gopts, err := headerGenOpts(key, header)
+	targets := s.targets()
+	gopts, err := generator.headerOpts(key, header, "", targets)
 	if err != nil {
 		return nil, err
 	}
-
-	datagen, found := generator.For(gopts)
-	if !found {
-		return nil, fmt.Errorf("no generator found for header [%s]", key)
-	}
-
-	return datagen(gopts)
+	return s.generate(generator, gopts, "header ["+key+"]", targets, checkHeader(key, header))
 }
 
 // GenSchema generates a random value for a schema
 func (s *Generator) GenSchema(key string, schema *spec.Schema) (interface{}, error) {
-	generator, err := newGenerator(s.Language)
+	generator, err := s.generators()
 	if err != nil {
 		return nil, err
 	}
-
-	gopts, err := schemaGenOpts(key, true, schema)
+	targets := s.targets()
+	gopts, err := generator.schemaOpts(key, schema, "", targets)
 	if err != nil {
 		return nil, err
 	}
+	return s.generate(generator, gopts, "schema ["+key+"]", targets, generator.checkSchema(key, schema, gopts.schema))
+}
 
-	datagen, found := generator.For(gopts)
+// generate generates a value for the options, the value is verified with the checker when the generator verifies its values.
+// The description of the descriptor is used in error messages.
+func (s *Generator) generate(generator *generators, opts GeneratorOpts, description string, targets modeTargets, check checker) (interface{}, error) {
+	datagen, found := generator.For(opts)
 	if !found {
-		return nil, fmt.Errorf("no generator found for header [%s]", key)
+		return nil, fmt.Errorf("no generator found for %s", description)
 	}
+	return s.verify(opts.FieldName(), targets, func() (interface{}, error) { return datagen(opts) }, check)
+}
 
-	return datagen(gopts)
+// paramOpts returns the options for a parameter which isn't a body parameter, located at the path
+func (g *generators) paramOpts(key string, param *spec.Parameter, path string, targets modeTargets) (*simpleOpts, error) {
+	gopts, err := paramGenOpts(key, param)
+	if err != nil {
+		return nil, err
+	}
+	gopts.locate(path, targets)
+	gopts.gens = g
+	return gopts, nil
+}
+
+// schemaOpts returns the options for a schema located at the path, references in the schema are resolved
+func (g *generators) schemaOpts(key string, schema *spec.Schema, path string, targets modeTargets) (*schemaOpts, error) {
+	resolved, err := g.resolve(schema, "")
+	if err != nil {
+		return nil, err
+	}
+	gopts, err := schemaGenOpts(key, true, resolved.schema)
+	if err != nil {
+		return nil, err
+	}
+	gopts.base, gopts.extraPatterns = resolved.base, resolved.patterns
+	if resolved.ref != "" {
+		gopts.ref, gopts.refs = resolved.ref, []string{resolved.ref}
+		if err := gopts.refGenOpts(schema); err != nil {
+			return nil, err
+		}
+	}
+	gopts.locate(path, targets)
+	gopts.gens = g
+	return gopts, nil
 }
@@ -1 +1,209 @@
 package stubs
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/go-openapi/spec"
+	"github.com/go-openapi/strfmt"
+	"github.com/go-openapi/swag"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGenerator_GenSchemaFallback(t *testing.T) {
+	gen := new(Generator)
+
+	res, err := gen.GenSchema("", spec.Int64Property())
+	if assert.NoError(t, err) {
+		assert.IsType(t, int64(0), res)
+	}
+
+	res, err = gen.GenSchema("count", spec.Int32Property())
+	if assert.NoError(t, err) {
+		assert.IsType(t, int32(0), res)
+	}
+
+	res, err = gen.GenSchema("", new(spec.Schema).Typed("null", ""))
+	if assert.NoError(t, err) {
+		assert.Nil(t, res)
+	}
+}
+
+func TestGenerator_GenSchemaUntyped(t *testing.T) {
+	gen := new(Generator)
+
+	res, err := gen.GenSchema("contact", &spec.Schema{SchemaProps: spec.SchemaProps{Format: "email"}})
+	if assert.NoError(t, err) && assert.IsType(t, "", res) {
+		assert.Contains(t, res, "@")
+	}
+	res, err = gen.GenSchema("", &spec.Schema{SchemaProps: spec.SchemaProps{Format: "int32"}})
+	if assert.NoError(t, err) {
+		assert.IsType(t, int32(0), res)
+	}
+	res, err = gen.GenSchema("", new(spec.Schema))
+	if assert.NoError(t, err) {
+		assert.IsType(t, map[string]interface{}{}, res)
+	}
+
+	assert.Equal(t, "string", inferType(new(spec.Schema).WithPattern(`^[A-Z]{3}$`)))
+	assert.Equal(t, "integer", inferType(new(spec.Schema).WithEnum(1, 2, 3)))
+	assert.Equal(t, "string", inferType(new(spec.Schema).WithEnum("a", "b")))
+	assert.Equal(t, "number", inferType(new(spec.Schema).WithEnum(1, 2.5)))
+	assert.Equal(t, "boolean", inferType(new(spec.Schema).WithEnum(true, nil)))
+	assert.Equal(t, "object", inferType(new(spec.Schema).WithEnum("a", 1)))
+	assert.Equal(t, "array", inferType(&spec.Schema{SchemaProps: spec.SchemaProps{Items: &spec.SchemaOrArray{Schema: spec.StringProperty()}}}))
+}
+
+func TestGenerator_GenParameterFallback(t *testing.T) {
+	gen := new(Generator)
+
+	res, err := gen.GenParameter("", spec.QueryParam("limit").Typed("integer", "int32"))
+	if assert.NoError(t, err) {
+		assert.IsType(t, int32(0), res)
+	}
+
+	res, err = gen.GenParameter("", spec.BodyParam("body", spec.BoolProperty()))
+	if assert.NoError(t, err) {
+		assert.IsType(t, true, res)
+	}
+}
+
+func petSchema() *spec.Schema {
+	return new(spec.Schema).
+		Typed("object", "").
+		WithRequired("id", "first-name", "email").
+		SetProperty("id", *spec.Int64Property()).
+		SetProperty("first-name", *spec.StringProperty()).
+		SetProperty("email", *spec.StringProperty()).
+		SetProperty("city", *spec.StringProperty()).
+		SetProperty("address", *new(spec.Schema).
+			Typed("object", "").
+			WithRequired("city").
+			SetProperty("city", *spec.StringProperty()))
+}
+
+func TestGenerator_GenSchemaObject(t *testing.T) {
+	gen := new(Generator)
+
+	for i := 0; i < 20; i++ {
+		res, err := gen.GenSchema("Pet", petSchema())
+		if assert.NoError(t, err) && assert.IsType(t, map[string]interface{}{}, res) {
+			pet := res.(map[string]interface{})
+			assert.IsType(t, int64(0), pet["id"])
+			assert.IsType(t, "", pet["first-name"])
+			assert.Contains(t, pet["email"], "@")
+			for k := range pet {
+				assert.Contains(t, []string{"id", "first-name", "email", "city", "address"}, k)
+			}
+			if addr, ok := pet["address"]; ok && assert.IsType(t, map[string]interface{}{}, addr) {
+				assert.NotEmpty(t, addr.(map[string]interface{})["city"])
+			}
+		}
+	}
+}
+
+func TestGenerator_GenSchemaArray(t *testing.T) {
+	gen := new(Generator)
+
+	schema := spec.ArrayProperty(petSchema())
+	schema.MinItems = swag.Int64(3)
+	schema.MaxItems = swag.Int64(3)
+	res, err := gen.GenSchema("pets", schema)
+	if assert.NoError(t, err) && assert.IsType(t, []interface{}{}, res) {
+		pets := res.([]interface{})
+		assert.Len(t, pets, 3)
+		for _, pet := range pets {
+			assert.IsType(t, map[string]interface{}{}, pet)
+		}
+	}
+
+	header := spec.ResponseHeader().CollectionOf(spec.NewItems().Typed("string", "uuid"), "csv")
+	res, err = gen.GenHeader("X-Request-Ids", header)
+	if assert.NoError(t, err) && assert.IsType(t, []interface{}{}, res) {
+		for _, id := range res.([]interface{}) {
+			assert.Regexp(t, strfmt.UUIDPattern, id)
+		}
+	}
+}
+
+func TestGenerator_Modes(t *testing.T) {
+	owner := new(spec.Schema).
+		Typed("object", "").
+		SetProperty("name", *spec.StringProperty()).
+		SetProperty("email", *spec.StringProperty().WithPattern(`^[a-z]+@[a-z]+\.com$`))
+	schema := new(spec.Schema).
+		Typed("object", "").
+		WithRequired("id").
+		SetProperty("id", *spec.Int64Property().WithMinimum(1, false)).
+		SetProperty("owner", *owner).
+		SetProperty("tags", *spec.ArrayProperty(spec.StringProperty().WithMaxLength(5)))
+
+	gen := &Generator{Modes: map[string]StubMode{"/owner/email": InvalidPattern}}
+	for i := 0; i < 20; i++ {
+		res, err := gen.GenSchema("Pet", schema)
+		if assert.NoError(t, err) {
+			pet := res.(map[string]interface{})
+			if assert.Contains(t, pet, "owner") {
+				assert.NotRegexp(t, `^[a-z]+@[a-z]+\.com$`, pet["owner"].(map[string]interface{})["email"])
+			}
+			assert.True(t, pet["id"].(int64) >= 1)
+		}
+	}
+
+	gen = &Generator{Modes: map[string]StubMode{"/tags/2": InvalidMaxLength, "/id": InvalidRequired}}
+	for i := 0; i < 20; i++ {
+		res, err := gen.GenSchema("Pet", schema)
+		if assert.NoError(t, err) {
+			pet := res.(map[string]interface{})
+			assert.NotContains(t, pet, "id")
+			if assert.Contains(t, pet, "tags") {
+				tags := pet["tags"].([]interface{})
+				if assert.True(t, len(tags) >= 3) {
+					for i, tag := range tags {
+						assert.Equal(t, i == 2, len(tag.(string)) > 5, "tag %d: %q", i, tag)
+					}
+				}
+			}
+		}
+	}
+
+	gen = &Generator{Mode: InvalidRequired}
+	res, err := gen.GenParameter("", spec.HeaderParam("X-Rate-Limit").Typed("integer", "int32").AsRequired())
+	if assert.NoError(t, err) {
+		assert.Nil(t, res)
+	}
+
+	gen = &Generator{Mode: InvalidMaximum}
+	_, err = gen.GenParameter("", spec.QueryParam("limit").Typed("integer", "int32"))
+	assert.Error(t, err)
+}
+
+func TestGenerator_Source(t *testing.T) {
+	schema := petSchema().
+		SetProperty("id", *spec.StringProperty().WithPattern(`^[a-z]{3}-\d{4}$`)).
+		SetProperty("uid", *spec.StrFmtProperty("uuid")).
+		SetProperty("ip", *spec.StringProperty()).
+		SetProperty("noun", *spec.StringProperty()).
+		SetProperty("streetAddress", *spec.StringProperty()).
+		SetProperty("secondaryAddress", *spec.StringProperty()).
+		SetProperty("postcode", *spec.StringProperty()).
+		SetProperty("landline", *spec.StringProperty()).
+		SetProperty("mobile", *spec.StringProperty()).
+		SetProperty("tags", *spec.ArrayProperty(spec.StringProperty()).UniqueValues()).
+		SetProperty("status", *spec.StringProperty().WithEnum("available", "pending", "sold"))
+
+	generate := func(seed int64) []interface{} {
+		gen := &Generator{Source: rand.NewSource(seed), Mode: Invalid}
+		var results []interface{}
+		for i := 0; i < 10; i++ {
+			res, err := gen.GenSchema("Pet", schema)
+			if assert.NoError(t, err) {
+				results = append(results, res)
+			}
+		}
+		return results
+	}
+
+	assert.Equal(t, generate(42), generate(42))
+	assert.NotEqual(t, generate(42), generate(43))
+}
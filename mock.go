@@ -0,0 +1,401 @@
+package stubs
+
+import (
+	"fmt"
+	"mime"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-openapi/loads"
+	"github.com/go-openapi/spec"
+)
+
+// Mock is an http.Handler which answers the requests for the operations of a document with generated responses,
+// so clients can be developed against an API without a meaningful implementation.
+//
+// A request is routed to an operation by its method and path, and is answered with a response generated for the success response
+// of the operation in a media type the operation produces and the request accepts.
+// A request with parameters or a body which don't validate against the operation is answered with its error response instead.
+//
+// A request can ask for a scenario with control headers or query flags: the response for a status code (X-Stub-Status: 404,
+// Prefer: code=404 or ?__code=404), a body generated for a stub mode (X-Stub-Mode: maxLength or ?__mode=maxLength)
+// and a delay (X-Stub-Delay: 250ms or ?__delay=250ms) up to the max delay of the mock.
+//
+// A stateful mock keeps the resources of the collections it infers from the paths (eg. /pets and /pets/{petId}) in memory,
+// so a resource which is created can be read, listed, replaced and deleted.
+type Mock struct {
+	// Generator generates the responses, its stub modes apply to every response
+	Generator *Generator
+
+	// Latency delays every response, a request can ask for another delay
+	Latency time.Duration
+
+	// MaxDelay caps the delay a request asks for, DefaultMaxDelay when it's 0
+	MaxDelay time.Duration
+
+	// Stateful stores the resources created with POST and PUT requests and answers the GET and DELETE requests for them,
+	// the read only properties and the identifier of a resource are generated when they aren't sent.
+	// A request for a resource which isn't stored is answered with a generated response.
+	Stateful bool
+
+	// lock serializes the generation of responses, a generator with a source can't generate stubs concurrently
+	lock   sync.Mutex
+	once   sync.Once
+	routes []route
+	err    error
+	store  map[string]*collection
+}
+
+// DefaultMaxDelay caps the delay a request asks a mock for, when the mock doesn't have a max delay
+const DefaultMaxDelay = 30 * time.Second
+
+// route is an operation of the document, the segments of its path template are matched against the path of a request
+type route struct {
+	method    string
+	segments  []string
+	literals  int
+	operation *spec.Operation
+	produces  []string
+	params    []spec.Parameter
+	// resource is the name of the path parameter which identifies the resources of the collection of the route,
+	// item is true when the route is for a resource rather than the collection
+	resource string
+	item     bool
+}
+
+// NewMock creates a mock for the operations of a document
+func NewMock(doc *loads.Document) *Mock {
+	return &Mock{Generator: NewGenerator(doc)}
+}
+
+// ServeHTTP answers a request with a generated response for the operation it's routed to.
+// A request for a path without operations is not found and a request for a method without an operation for the path is not allowed,
+// a request which doesn't accept a media type the operation produces is not acceptable.
+// A request which doesn't validate against the operation is rejected with its 400 or 422 response, or its default response as a 400,
+// unless the request asks for the response with a status code.
+// A request which is routed to an operation is delayed, a request which isn't routed is answered at once.
+func (m *Mock) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	m.once.Do(m.buildRoutes)
+	if m.err != nil {
+		http.Error(w, m.err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	sc, err := parseScenario(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	rt, allowed := m.route(r.Method, r.URL.Path)
+	if rt == nil {
+		if len(allowed) == 0 {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Allow", strings.Join(allowed, ", "))
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+	if !sleep(r.Context(), m.delay(sc)) {
+		return
+	}
+
+	mediaType, ok := acceptable(r.Header.Get("Accept"), rt.produces)
+	if !ok {
+		http.Error(w, fmt.Sprintf("%s only produces %s", rt.operation.ID, strings.Join(rt.produces, ", ")), http.StatusNotAcceptable)
+		return
+	}
+
+	statusCode := sc.status
+	if statusCode == 0 {
+		errs, err := m.validateRequest(r, rt)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if len(errs) > 0 {
+			m.reject(w, rt, mediaType, sc.mode, errs)
+			return
+		}
+		if m.Stateful && sc.mode == Valid && m.serveResource(w, r, rt, mediaType) {
+			return
+		}
+		statusCode = successStatus(rt.operation)
+	} else if _, _, err := m.Generator.declaredResponse(rt.operation, statusCode); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	m.lock.Lock()
+	resp, err := m.Generator.genResponse(rt.operation, statusCode, rt.produces, sc.mode)
+	m.lock.Unlock()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeResponse(w, resp, mediaType)
+}
+
+// delay returns how long a response is delayed, the latency of the mock or the delay the request asks for up to the max delay
+func (m *Mock) delay(sc scenario) time.Duration {
+	if !sc.delayed {
+		return m.Latency
+	}
+	maxDelay := m.MaxDelay
+	if maxDelay <= 0 {
+		maxDelay = DefaultMaxDelay
+	}
+	if sc.delay > maxDelay {
+		return maxDelay
+	}
+	return sc.delay
+}
+
+// buildRoutes creates the routes for the operations of the document of the generator,
+// routes with more literal segments take precedence (eg. /pets/mine over /pets/{petId})
+func (m *Mock) buildRoutes() {
+	analyzed, err := m.Generator.analyzer()
+	if err != nil {
+		m.err = err
+		return
+	}
+
+	for method, operations := range analyzed.Operations() {
+		for path, operation := range operations {
+			rt := route{
+				method:    method,
+				segments:  strings.Split(strings.Trim(path, "/"), "/"),
+				operation: operation,
+				produces:  analyzed.ProducesFor(operation),
+			}
+			byKey := analyzed.ParamsFor(method, path)
+			keys := make([]string, 0, len(byKey))
+			for key := range byKey {
+				keys = append(keys, key)
+			}
+			// the parameters are validated in a fixed order, so the same request gets the same messages
+			sort.Strings(keys)
+			for _, key := range keys {
+				rt.params = append(rt.params, byKey[key])
+			}
+			for _, segment := range rt.segments {
+				if !isPathParam(segment) {
+					rt.literals++
+				}
+			}
+			m.routes = append(m.routes, rt)
+		}
+	}
+	sort.Slice(m.routes, func(i, j int) bool {
+		a, b := m.routes[i], m.routes[j]
+		if a.literals != b.literals {
+			return a.literals > b.literals
+		}
+		if pa, pb := strings.Join(a.segments, "/"), strings.Join(b.segments, "/"); pa != pb {
+			return pa < pb
+		}
+		return a.method < b.method
+	})
+	m.inferResources()
+}
+
+// inferResources infers the collections from the path templates of the routes: a route which ends with a path parameter is for
+// a resource of a collection, the route for the path without the parameter is for the collection (eg. /pets/{petId} and /pets)
+func (m *Mock) inferResources() {
+	items := make(map[string]string)
+	for i := range m.routes {
+		rt := &m.routes[i]
+		if last := rt.segments[len(rt.segments)-1]; isPathParam(last) {
+			rt.resource, rt.item = strings.Trim(last, "{}"), true
+			items[strings.Join(rt.segments[:len(rt.segments)-1], "/")] = rt.resource
+		}
+	}
+	for i := range m.routes {
+		rt := &m.routes[i]
+		if !rt.item {
+			rt.resource = items[strings.Join(rt.segments, "/")]
+		}
+	}
+}
+
+// route returns the route for a method and the path of a request, the path includes the base path of the document.
+// When no route matches it returns the methods of the routes which match the path.
+func (m *Mock) route(method, path string) (*route, []string) {
+	segments, ok := m.segments(path)
+	if !ok {
+		return nil, nil
+	}
+
+	var allowed []string
+	var matched string
+	for i := range m.routes {
+		rt := &m.routes[i]
+		if !rt.matches(segments) {
+			continue
+		}
+		template := strings.Join(rt.segments, "/")
+		if len(allowed) > 0 && matched != template {
+			// a route with more literal segments matched the path
+			continue
+		}
+		matched = template
+		if rt.method == strings.ToUpper(method) {
+			return rt, nil
+		}
+		allowed = append(allowed, rt.method)
+	}
+	sort.Strings(allowed)
+	return nil, allowed
+}
+
+// segments splits the path of a request into the segments after the base path of the document,
+// it returns false for a path outside of the base path
+func (m *Mock) segments(path string) ([]string, bool) {
+	basePath := strings.TrimSuffix(m.Generator.Spec.BasePath, "/")
+	if !strings.HasPrefix(path, basePath+"/") && path != basePath {
+		return nil, false
+	}
+	return strings.Split(strings.TrimPrefix(strings.TrimPrefix(path, basePath), "/"), "/"), true
+}
+
+// matches returns true when the segments of a path match the path template of the route,
+// a path parameter matches any segment which isn't empty
+func (rt *route) matches(segments []string) bool {
+	if len(segments) != len(rt.segments) {
+		return false
+	}
+	for i, segment := range rt.segments {
+		if isPathParam(segment) {
+			if segments[i] == "" {
+				return false
+			}
+			continue
+		}
+		if segment != segments[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func isPathParam(segment string) bool {
+	return strings.HasPrefix(segment, "{") && strings.HasSuffix(segment, "}")
+}
+
+// successStatus returns the status code of the success response of an operation, the lowest declared 2xx status code.
+// An operation without a declared success response answers with its default response as 200.
+func successStatus(operation *spec.Operation) int {
+	status := http.StatusOK
+	if operation.Responses == nil {
+		return status
+	}
+	codes := make([]int, 0, len(operation.Responses.StatusCodeResponses))
+	for code := range operation.Responses.StatusCodeResponses {
+		codes = append(codes, code)
+	}
+	sort.Ints(codes)
+	for _, code := range codes {
+		if code >= 200 && code < 300 {
+			return code
+		}
+	}
+	return status
+}
+
+// acceptable picks the media type for a response from the media types an operation produces and the accept header of a request,
+// only media types a body can be encoded in are considered. An operation without media types produces json.
+func acceptable(accept string, produces []string) (string, bool) {
+	var candidates []string
+	for _, mediaType := range produces {
+		if encodable(mediaType) {
+			candidates = append(candidates, mediaType)
+		}
+	}
+	if len(produces) == 0 {
+		candidates = []string{jsonMime}
+	}
+	if len(candidates) == 0 {
+		return "", false
+	}
+	if strings.TrimSpace(accept) == "" {
+		return negotiate(candidates, jsonMime), true
+	}
+
+	var best string
+	var bestQuality float64
+	for _, mediaType := range candidates {
+		if q := quality(accept, mediaType); q > bestQuality {
+			best, bestQuality = mediaType, q
+		}
+	}
+	return best, bestQuality > 0
+}
+
+// quality returns the quality the accept header gives a media type, the most specific media range which matches determines the quality
+func quality(accept, mediaType string) float64 {
+	mt, _, err := mime.ParseMediaType(mediaType)
+	if err != nil {
+		return 0
+	}
+	var q float64
+	specificity := -1
+	for _, part := range strings.Split(accept, ",") {
+		rng, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		var s int
+		switch {
+		case rng == mt:
+			s = 2
+		case strings.HasSuffix(rng, "/*") && strings.HasPrefix(mt, strings.TrimSuffix(rng, "*")):
+			s = 1
+		case rng == "*/*":
+			s = 0
+		default:
+			continue
+		}
+		if s <= specificity {
+			continue
+		}
+		specificity, q = s, 1
+		if v, ok := params["q"]; ok {
+			if parsed, err := strconv.ParseFloat(v, 64); err == nil {
+				q = parsed
+			}
+		}
+	}
+	return q
+}
+
+// writeResponse writes the headers and the body of a response stub, the body is encoded in the media type
+func writeResponse(w http.ResponseWriter, resp *Response, mediaType string) {
+	for name, values := range resp.Header {
+		w.Header()[name] = append([]string(nil), values...)
+	}
+	if resp.Body == nil {
+		w.WriteHeader(statusOf(resp))
+		return
+	}
+
+	data, err := encodeValue(resp.Body, mediaType)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", mediaType)
+	w.WriteHeader(statusOf(resp))
+	_, _ = w.Write(data)
+}
+
+// statusOf returns the status code to answer with for a response stub, a default response without a status code is a 200
+func statusOf(resp *Response) int {
+	if resp.StatusCode == 0 {
+		return http.StatusOK
+	}
+	return resp.StatusCode
+}
@@ -0,0 +1,462 @@
+package stubs
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-openapi/loads"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMock(t *testing.T) {
+	doc, err := loads.Spec("fixtures/petstore.yaml")
+	if !assert.NoError(t, err) {
+		return
+	}
+	mock := NewMock(doc)
+	mock.Generator.Verify = true
+
+	serve := func(method, path, accept string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, nil)
+		if accept != "" {
+			req.Header.Set("Accept", accept)
+		}
+		rec := httptest.NewRecorder()
+		mock.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := serve("GET", "/api/pets", "")
+	if assert.Equal(t, http.StatusOK, rec.Code, rec.Body.String()) {
+		assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+		var pets []map[string]interface{}
+		assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &pets))
+		limit, err := strconv.Atoi(rec.Header().Get("X-Rate-Limit"))
+		if assert.NoError(t, err) {
+			assert.True(t, limit >= 0 && limit <= 1000)
+		}
+	}
+
+	rec = serve("GET", "/api/pets/12", "text/html, application/json;q=0.9")
+	if assert.Equal(t, http.StatusOK, rec.Code, rec.Body.String()) {
+		var pet map[string]interface{}
+		if assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &pet)) {
+			assert.NotEmpty(t, pet["name"])
+		}
+	}
+
+	req := httptest.NewRequest("POST", "/api/pets", strings.NewReader(`{"id":1,"name":"Rex"}`))
+	req.Header.Set("Accept", "*/*")
+	rec = httptest.NewRecorder()
+	mock.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+	rec = serve("DELETE", "/api/pets/12", "")
+	assert.Equal(t, http.StatusNoContent, rec.Code, rec.Body.String())
+	assert.Empty(t, rec.Body.String())
+
+	assert.Equal(t, http.StatusNotFound, serve("GET", "/api/owners", "").Code)
+	assert.Equal(t, http.StatusNotFound, serve("GET", "/pets", "").Code)
+	assert.Equal(t, http.StatusNotFound, serve("GET", "/api/pets/", "").Code)
+	rec = serve("PATCH", "/api/pets", "")
+	assert.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+	assert.Equal(t, "GET, POST", rec.Header().Get("Allow"))
+	assert.Equal(t, http.StatusNotAcceptable, serve("GET", "/api/pets", "application/xml").Code)
+}
+
+func TestMock_Validation(t *testing.T) {
+	doc, err := loads.Spec("fixtures/petstore.yaml")
+	if !assert.NoError(t, err) {
+		return
+	}
+	mock := NewMock(doc)
+	mock.Generator.Verify = true
+
+	serve := func(method, path, contentType string, body io.Reader) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, body)
+		if contentType != "" {
+			req.Header.Set("Content-Type", contentType)
+		}
+		rec := httptest.NewRecorder()
+		mock.ServeHTTP(rec, req)
+		return rec
+	}
+	message := func(rec *httptest.ResponseRecorder) string {
+		var body map[string]interface{}
+		if !assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body), rec.Body.String()) {
+			return ""
+		}
+		assert.IsType(t, float64(0), body["code"])
+		msg, _ := body["message"].(string)
+		return msg
+	}
+
+	// the requests generated for the operations are valid
+	gen := NewGenerator(doc)
+	gen.Verify = true
+	for i := 0; i < 20; i++ {
+		for _, operationID := range []string{"listPets", "createPet", "getPet", "updatePet", "deletePet", "uploadPhoto"} {
+			stub, err := gen.GenRequest(operationID)
+			if !assert.NoError(t, err) {
+				return
+			}
+			req, err := stub.HTTPRequest("http://localhost")
+			if !assert.NoError(t, err) {
+				return
+			}
+			rec := httptest.NewRecorder()
+			mock.ServeHTTP(rec, req)
+			assert.True(t, rec.Code >= 200 && rec.Code < 300, "%s: %d %s", operationID, rec.Code, rec.Body.String())
+		}
+	}
+
+	// listPets rejects with its default response as a 400
+	rec := serve("GET", "/api/pets?limit=500", "", nil)
+	if assert.Equal(t, http.StatusBadRequest, rec.Code) {
+		assert.Contains(t, message(rec), "limit")
+	}
+	rec = serve("GET", "/api/pets?limit=ten", "", nil)
+	if assert.Equal(t, http.StatusBadRequest, rec.Code) {
+		assert.Contains(t, message(rec), "limit")
+	}
+	rec = serve("GET", "/api/pets?status=available&status=lost", "", nil)
+	if assert.Equal(t, http.StatusBadRequest, rec.Code) {
+		assert.Contains(t, message(rec), "status")
+	}
+	assert.Equal(t, http.StatusOK, serve("GET", "/api/pets?limit=10&tags=a,b&status=sold", "", nil).Code)
+
+	// createPet rejects with its 422 response
+	rec = serve("POST", "/api/pets", "application/json", strings.NewReader(`{"id":1,"name":""}`))
+	if assert.Equal(t, http.StatusUnprocessableEntity, rec.Code) {
+		assert.Contains(t, message(rec), "name")
+	}
+	rec = serve("POST", "/api/pets", "application/json", strings.NewReader(`{"id":1,`))
+	if assert.Equal(t, http.StatusUnprocessableEntity, rec.Code) {
+		assert.Contains(t, message(rec), "pet")
+	}
+	rec = serve("POST", "/api/pets", "", nil)
+	if assert.Equal(t, http.StatusUnprocessableEntity, rec.Code) {
+		assert.Contains(t, message(rec), "pet")
+	}
+	rec = serve("POST", "/api/pets", "application/json", strings.NewReader(`{"id":1,"name":"Rex","owner":{"email":"nobody"}}`))
+	assert.Equal(t, http.StatusUnprocessableEntity, rec.Code, rec.Body.String())
+
+	// operations without an error response reject with the messages as text
+	rec = serve("GET", "/api/pets/abc", "", nil)
+	if assert.Equal(t, http.StatusBadRequest, rec.Code) {
+		assert.Contains(t, rec.Body.String(), "petId")
+	}
+	assert.Equal(t, http.StatusBadRequest, serve("DELETE", "/api/pets/0", "", nil).Code)
+
+	var buf bytes.Buffer
+	w := multipart.NewWriter(&buf)
+	assert.NoError(t, w.WriteField("caption", "a caption"))
+	assert.NoError(t, w.Close())
+	rec = serve("POST", "/api/pets/12/photos", w.FormDataContentType(), &buf)
+	if assert.Equal(t, http.StatusBadRequest, rec.Code) {
+		assert.Contains(t, rec.Body.String(), "photo")
+	}
+}
+
+func TestMock_OptionalMessage(t *testing.T) {
+	doc, err := loads.Spec("fixtures/petstore.yaml")
+	if !assert.NoError(t, err) {
+		return
+	}
+	// the message of an error isn't always generated when it's optional
+	errorSchema := doc.Spec().Definitions["Error"]
+	errorSchema.Required = []string{"code"}
+	doc.Spec().Definitions["Error"] = errorSchema
+	mock := NewMock(doc)
+
+	for i := 0; i < 20; i++ {
+		rec := httptest.NewRecorder()
+		mock.ServeHTTP(rec, httptest.NewRequest("GET", "/api/pets?limit=500", nil))
+		if !assert.Equal(t, http.StatusBadRequest, rec.Code) {
+			continue
+		}
+		var body map[string]interface{}
+		if assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body), rec.Body.String()) {
+			assert.Contains(t, body["message"], "limit")
+		}
+	}
+}
+
+func TestMock_Scenario(t *testing.T) {
+	doc, err := loads.Spec("fixtures/petstore.yaml")
+	if !assert.NoError(t, err) {
+		return
+	}
+	mock := NewMock(doc)
+	mock.Generator.Verify = true
+
+	serve := func(method, path string, header http.Header) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, nil)
+		for name, values := range header {
+			req.Header[name] = values
+		}
+		rec := httptest.NewRecorder()
+		mock.ServeHTTP(rec, req)
+		return rec
+	}
+	body := func(rec *httptest.ResponseRecorder) map[string]interface{} {
+		var value map[string]interface{}
+		assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &value), rec.Body.String())
+		return value
+	}
+
+	// a requested status code skips the validation of the request
+	rec := serve("GET", "/api/pets/12", http.Header{StatusHeader: {"404"}})
+	if assert.Equal(t, http.StatusNotFound, rec.Code, rec.Body.String()) {
+		assert.Contains(t, body(rec), "message")
+	}
+	rec = serve("POST", "/api/pets", http.Header{"Prefer": {"dynamic=true; code=422"}})
+	assert.Equal(t, http.StatusUnprocessableEntity, rec.Code, rec.Body.String())
+	rec = serve("GET", "/api/pets?__code=503", nil)
+	if assert.Equal(t, http.StatusServiceUnavailable, rec.Code, rec.Body.String()) {
+		assert.Contains(t, body(rec), "code")
+	}
+	rec = serve("GET", "/api/pets/12?__code=200", http.Header{StatusHeader: {"404"}})
+	assert.Equal(t, http.StatusNotFound, rec.Code, rec.Body.String())
+
+	assert.Equal(t, http.StatusBadRequest, serve("GET", "/api/pets/12", http.Header{StatusHeader: {"418"}}).Code)
+	assert.Equal(t, http.StatusBadRequest, serve("GET", "/api/pets/12", http.Header{StatusHeader: {"not found"}}).Code)
+
+	// a stub mode applies to the body of the response
+	for i := 0; i < 10; i++ {
+		rec = serve("GET", "/api/pets/12", http.Header{ModeHeader: {"maxLength"}})
+		if assert.Equal(t, http.StatusOK, rec.Code, rec.Body.String()) {
+			name, _ := body(rec)["name"].(string)
+			assert.True(t, len(name) > 40, name)
+		}
+	}
+	rec = serve("GET", "/api/pets/abc?__mode=minLength", http.Header{"Accept": {"application/json"}})
+	if assert.Equal(t, http.StatusBadRequest, rec.Code, rec.Body.String()) {
+		assert.Contains(t, rec.Body.String(), "petId")
+	}
+	assert.Equal(t, http.StatusBadRequest, serve("GET", "/api/pets/12", http.Header{ModeHeader: {"lenient"}}).Code)
+	assert.Equal(t, http.StatusInternalServerError, serve("DELETE", "/api/pets/12", http.Header{ModeHeader: {"invalid"}}).Code)
+
+	// the latency of the mock delays every response, unless a request asks for another delay
+	mock.Latency = 30 * time.Millisecond
+	start := time.Now()
+	assert.Equal(t, http.StatusNoContent, serve("DELETE", "/api/pets/12", nil).Code)
+	assert.True(t, time.Since(start) >= 30*time.Millisecond)
+	start = time.Now()
+	assert.Equal(t, http.StatusNoContent, serve("DELETE", "/api/pets/12?__delay=60", nil).Code)
+	assert.True(t, time.Since(start) >= 60*time.Millisecond)
+	assert.Equal(t, http.StatusNoContent, serve("DELETE", "/api/pets/12", http.Header{DelayHeader: {"0s"}}).Code)
+	assert.Equal(t, http.StatusBadRequest, serve("DELETE", "/api/pets/12", http.Header{DelayHeader: {"-1s"}}).Code)
+
+	// the delay a request asks for is capped, a request which isn't routed to an operation isn't delayed
+	mock.MaxDelay = 20 * time.Millisecond
+	start = time.Now()
+	assert.Equal(t, http.StatusNoContent, serve("DELETE", "/api/pets/12", http.Header{DelayHeader: {"1h"}}).Code)
+	assert.True(t, time.Since(start) < time.Second)
+	start = time.Now()
+	assert.Equal(t, http.StatusNotFound, serve("GET", "/api/owners", http.Header{DelayHeader: {"1h"}}).Code)
+	assert.Equal(t, http.StatusMethodNotAllowed, serve("PATCH", "/api/pets", http.Header{DelayHeader: {"1h"}}).Code)
+	assert.True(t, time.Since(start) < time.Second)
+	mock.MaxDelay = 0
+	assert.Equal(t, DefaultMaxDelay, mock.delay(scenario{delay: time.Hour, delayed: true}))
+	assert.Equal(t, mock.Latency, mock.delay(scenario{}))
+
+	// a request which is cancelled during the delay isn't answered
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	req := httptest.NewRequest("DELETE", "/api/pets/12", nil).WithContext(ctx)
+	req.Header.Set(DelayHeader, "1h")
+	rec = httptest.NewRecorder()
+	mock.ServeHTTP(rec, req)
+	assert.Empty(t, rec.Header())
+	assert.Empty(t, rec.Body.String())
+}
+
+func TestMock_Stateful(t *testing.T) {
+	doc, err := loads.Spec("fixtures/petstore.yaml")
+	if !assert.NoError(t, err) {
+		return
+	}
+	mock := NewMock(doc)
+	mock.Generator.Verify = true
+	mock.Stateful = true
+
+	serve := func(method, path, body string) (int, interface{}) {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		if body != "" {
+			req.Header.Set("Content-Type", "application/json")
+		}
+		rec := httptest.NewRecorder()
+		mock.ServeHTTP(rec, req)
+		var value interface{}
+		if rec.Header().Get("Content-Type") == "application/json" {
+			dec := json.NewDecoder(rec.Body)
+			dec.UseNumber()
+			assert.NoError(t, dec.Decode(&value))
+		}
+		return rec.Code, value
+	}
+
+	code, list := serve("GET", "/api/pets", "")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, []interface{}{}, list)
+
+	// the read only id is generated
+	code, created := serve("POST", "/api/pets", `{"id":1,"name":"Rex","tags":[{"name":"good"}]}`)
+	if !assert.Equal(t, http.StatusCreated, code, created) {
+		return
+	}
+	pet := created.(map[string]interface{})
+	assert.Equal(t, "Rex", pet["name"])
+	id := pet["id"].(json.Number).String()
+	assert.NotEqual(t, "1", id)
+
+	code, got := serve("GET", "/api/pets/"+id, "")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, created, got)
+	code, list = serve("GET", "/api/pets", "")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, []interface{}{created}, list)
+
+	// the id of a replaced resource is the id in the path
+	code, updated := serve("PUT", "/api/pets/"+id, `{"id":1,"name":"Max"}`)
+	if assert.Equal(t, http.StatusOK, code, updated) {
+		assert.Equal(t, json.Number(id), updated.(map[string]interface{})["id"])
+		assert.Equal(t, "Max", updated.(map[string]interface{})["name"])
+	}
+	_, got = serve("GET", "/api/pets/"+id, "")
+	assert.Equal(t, updated, got)
+	code, _ = serve("PUT", "/api/pets/3000000000000", `{"id":1,"name":"Fido"}`)
+	assert.Equal(t, http.StatusOK, code)
+	_, list = serve("GET", "/api/pets", "")
+	assert.Len(t, list, 2)
+
+	// a deleted resource isn't found
+	code, _ = serve("DELETE", "/api/pets/"+id, "")
+	assert.Equal(t, http.StatusNoContent, code)
+	code, missing := serve("GET", "/api/pets/"+id, "")
+	if assert.Equal(t, http.StatusNotFound, code) {
+		assert.Contains(t, missing.(map[string]interface{})["message"], "is deleted")
+	}
+	code, _ = serve("DELETE", "/api/pets/"+id, "")
+	assert.Equal(t, http.StatusNotFound, code)
+	_, list = serve("GET", "/api/pets", "")
+	if assert.Len(t, list, 1) {
+		assert.Equal(t, "Fido", list.([]interface{})[0].(map[string]interface{})["name"])
+	}
+
+	// a resource which isn't stored is generated with the id in the path
+	code, generated := serve("GET", "/api/pets/4000000000000", "")
+	if assert.Equal(t, http.StatusOK, code, generated) {
+		assert.Equal(t, json.Number("4000000000000"), generated.(map[string]interface{})["id"])
+	}
+
+	// invalid requests and scenarios don't touch the store
+	code, _ = serve("POST", "/api/pets", `{"id":1,"name":""}`)
+	assert.Equal(t, http.StatusUnprocessableEntity, code)
+	req := httptest.NewRequest("GET", "/api/pets/3000000000000", nil)
+	req.Header.Set(StatusHeader, "404")
+	rec := httptest.NewRecorder()
+	mock.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+	_, list = serve("GET", "/api/pets", "")
+	assert.Len(t, list, 1)
+
+	// the generated ids of created resources are unique
+	ids := make(map[string]bool)
+	for i := 0; i < 50; i++ {
+		code, created := serve("POST", "/api/pets", `{"id":1,"name":"Rex"}`)
+		if assert.Equal(t, http.StatusCreated, code, created) {
+			ids[created.(map[string]interface{})["id"].(json.Number).String()] = true
+		}
+	}
+	assert.Len(t, ids, 50)
+	_, list = serve("GET", "/api/pets", "")
+	assert.Len(t, list, 51)
+}
+
+func TestMock_StatefulSentID(t *testing.T) {
+	doc, err := loads.Spec("fixtures/petstore.yaml")
+	if !assert.NoError(t, err) {
+		return
+	}
+	// the id of a pet is sent by the client
+	pet := doc.Spec().Definitions["Pet"]
+	id := pet.Properties["id"]
+	id.ReadOnly = false
+	pet.Properties["id"] = id
+	doc.Spec().Definitions["Pet"] = pet
+	mock := NewMock(doc)
+	mock.Stateful = true
+
+	post := func(body string) map[string]interface{} {
+		req := httptest.NewRequest("POST", "/api/pets", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		mock.ServeHTTP(rec, req)
+		var value map[string]interface{}
+		if assert.Equal(t, http.StatusCreated, rec.Code, rec.Body.String()) {
+			assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &value))
+		}
+		return value
+	}
+
+	// a sent id which is taken replaces the resource
+	assert.Equal(t, float64(7), post(`{"id":7,"name":"Rex"}`)["id"])
+	assert.Equal(t, float64(7), post(`{"id":7,"name":"Max"}`)["id"])
+	rec := httptest.NewRecorder()
+	mock.ServeHTTP(rec, httptest.NewRequest("GET", "/api/pets", nil))
+	var list []map[string]interface{}
+	if assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &list)) && assert.Len(t, list, 1) {
+		assert.Equal(t, "Max", list[0]["name"])
+	}
+}
+
+func TestEmbedMessages(t *testing.T) {
+	assert.Equal(t, "invalid", embedMessages("generated", nil, "invalid"))
+	assert.Equal(t, map[string]interface{}{"Detail": "invalid", "code": 1},
+		embedMessages(map[string]interface{}{"Detail": "generated", "code": 1}, nil, "invalid"))
+	assert.Equal(t, map[string]interface{}{"message": 2}, embedMessages(map[string]interface{}{"message": 2}, nil, "invalid"))
+	assert.Equal(t, []interface{}{"generated"}, embedMessages([]interface{}{"generated"}, nil, "invalid"))
+
+	// the message field of the schema is set when it isn't generated
+	assert.Equal(t, map[string]interface{}{"code": 1, "message": "invalid"},
+		embedMessages(map[string]interface{}{"code": 1}, []string{"message"}, "invalid"))
+	assert.Equal(t, map[string]interface{}{"code": 1, "error": "invalid", "title": "generated"},
+		embedMessages(map[string]interface{}{"code": 1, "title": "generated"}, []string{"error", "title"}, "invalid"))
+}
+
+func TestAcceptable(t *testing.T) {
+	produces := []string{"application/xml", "application/json", "text/plain"}
+
+	mediaType, ok := acceptable("", produces)
+	assert.True(t, ok)
+	assert.Equal(t, "application/json", mediaType)
+
+	mediaType, ok = acceptable("text/*", produces)
+	assert.True(t, ok)
+	assert.Equal(t, "text/plain", mediaType)
+
+	mediaType, ok = acceptable("application/json;q=0.5, text/plain;q=0.8", produces)
+	assert.True(t, ok)
+	assert.Equal(t, "text/plain", mediaType)
+
+	mediaType, ok = acceptable("*/*;q=0.1, text/plain;q=0", produces)
+	assert.True(t, ok)
+	assert.Equal(t, "application/json", mediaType)
+
+	_, ok = acceptable("application/xml", produces)
+	assert.False(t, ok)
+
+	mediaType, ok = acceptable("application/json", nil)
+	assert.True(t, ok)
+	assert.Equal(t, "application/json", mediaType)
+}
@@ -0,0 +1,921 @@
+package stubs
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/go-openapi/strfmt"
+	regen "github.com/zach-klippenstein/goregen"
+)
+
+const (
+	// the validations a collection violates itself, other validations are violated by its members
+	collectionModes = InvalidMaxItems | InvalidMinItems | InvalidUniqueItems
+
+	// the length of the range used for numbers that are only bounded on one side, or not at all
+	defaultNumericSpread = 1000
+	// the number of characters a string can exceed its max length with
+	defaultLengthSpread = 10
+	// the characters used to generate strings which don't match a pattern
+	invalidPatternChars = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789 -_.,;:!?@#$%&*()[]{}<>/\\|'\"~`^+="
+)
+
+// modeOpts overrides the stub mode of generator options
+type modeOpts struct {
+	GeneratorOpts
+	mode StubMode
+}
+
+func (m *modeOpts) Mode() StubMode {
+	return m.mode
+}
+
+func (m *modeOpts) recursion() error {
+	return recursion(m.GeneratorOpts)
+}
+
+func (m *modeOpts) Properties() ([]GeneratorOpts, error) {
+	return properties(m.GeneratorOpts)
+}
+
+func (m *modeOpts) Rand() *rand.Rand {
+	if sourced, ok := m.GeneratorOpts.(SourceOpts); ok {
+		return sourced.Rand()
+	}
+	return (*generators)(nil).random()
+}
+
+func (m *modeOpts) Generator(name string) (ValueGenerator, bool) {
+	if sourced, ok := m.GeneratorOpts.(SourceOpts); ok {
+		return sourced.Generator(name)
+	}
+	return nil, false
+}
+
+func (m *modeOpts) patterns() []string {
+	if patterned, ok := m.GeneratorOpts.(patternedOpts); ok {
+		return patterned.patterns()
+	}
+	return nil
+}
+
+func (m *modeOpts) discriminator() (string, string) {
+	if poly, ok := m.GeneratorOpts.(polymorphicOpts); ok {
+		return poly.discriminator()
+	}
+	return "", ""
+}
+
+func (m *modeOpts) subtype() (GeneratorOpts, error) {
+	poly, ok := m.GeneratorOpts.(polymorphicOpts)
+	if !ok {
+		return nil, nil
+	}
+	sub, err := poly.subtype()
+	if err != nil || sub == nil {
+		return nil, err
+	}
+	return withMode(sub, m.mode), nil
+}
+
+func (m *modeOpts) composition() (string, int) {
+	if composed, ok := m.GeneratorOpts.(composedOpts); ok {
+		return composed.composition()
+	}
+	return "", 0
+}
+
+func (m *modeOpts) derive(keyword string, indexes []int) (GeneratorOpts, error) {
+	derived, err := m.GeneratorOpts.(composedOpts).derive(keyword, indexes)
+	if err != nil {
+		return nil, err
+	}
+	return withMode(derived, m.mode), nil
+}
+
+func (m *modeOpts) exclusive(keyword string, indexes []int, value interface{}) (bool, error) {
+	return m.GeneratorOpts.(composedOpts).exclusive(keyword, indexes, value)
+}
+
+func (m *modeOpts) pointer() string {
+	if composed, ok := m.GeneratorOpts.(composedOpts); ok {
+		return composed.pointer()
+	}
+	return ""
+}
+
+func withMode(opts GeneratorOpts, mode StubMode) GeneratorOpts {
+	return &modeOpts{GeneratorOpts: opts, mode: mode}
+}
+
+// honorMode wraps a value generator so the value it generates violates exactly the validations selected by the stub mode.
+// The value satisfies all the validations that aren't selected, a valid value the generator can't produce is generated from the validations.
+// A panic of the value generator is returned as an error.
+func (g *generators) honorMode(datagen ValueGenerator) ValueGenerator {
+	return func(opts GeneratorOpts) (value interface{}, err error) {
+		defer func() {
+			// faker panics for an entry missing from its dictionary
+			if r := recover(); r != nil {
+				value, err = nil, fmt.Errorf("unable to generate a value for [%s]: %v", opts.FieldName(), r)
+			}
+		}()
+		if _, ok := opts.Enum(); ok && opts.Mode() == Valid {
+			// the enum takes precedence over the value generator
+			return g.conform(opts, datagen)
+		}
+		if opts.Mode() == Valid {
+			value, err = datagen(opts)
+			if err != nil || violations(opts, value) == Valid {
+				return value, err
+			}
+			return g.conform(opts, datagen)
+		}
+
+		mode, err := g.resolveMode(opts)
+		if err != nil {
+			return nil, err
+		}
+		return g.conform(withMode(opts, mode), datagen)
+	}
+}
+
+// resolveMode replaces the Invalid flag with a random validation that can be violated
+// and verifies all the selected validations can be violated for the options.
+func (g *generators) resolveMode(opts GeneratorOpts) (StubMode, error) {
+	mode := opts.Mode()
+	available := violable(opts)
+
+	if mode.Has(Invalid) {
+		mode &^= Invalid
+		var candidates []StubMode
+		for _, m := range validationModes {
+			if available.Has(m) && !mode.Has(m) {
+				candidates = append(candidates, m)
+			}
+		}
+		if len(candidates) == 0 && mode == Valid {
+			return Valid, fmt.Errorf("no validation can be violated for [%s]", opts.FieldName())
+		}
+		if len(candidates) > 0 {
+			mode |= candidates[g.rand.Intn(len(candidates))]
+		}
+	}
+
+	if impossible := mode &^ available; impossible != Valid {
+		return Valid, fmt.Errorf("can't generate a value for [%s] which is invalid for %s", opts.FieldName(), impossible)
+	}
+	return mode, nil
+}
+
+// violable returns the validations a generated value can violate for the options
+func violable(opts GeneratorOpts) StubMode {
+	var modes StubMode
+	if recursion(opts) != nil {
+		// a value nested too deep isn't generated
+		return modes
+	}
+	if enm, ok := opts.Enum(); ok && !(opts.Type() == "boolean" && enumContains(enm, true) && enumContains(enm, false)) {
+		// a boolean can't be outside an enum of both booleans
+		modes |= InvalidEnum
+	}
+
+	switch opts.Type() {
+	case "integer", "number":
+		if _, _, ok := opts.Maximum(); ok {
+			modes |= InvalidMaximum
+		}
+		if _, _, ok := opts.Minimum(); ok {
+			modes |= InvalidMinimum
+		}
+		if mo, ok := opts.MultipleOf(); ok && mo > 0 && !(opts.Type() == "integer" && isMultipleOf(1, mo)) {
+			modes |= InvalidMultipleOf
+		}
+	case "string":
+		if _, ok := opts.MaxLength(); ok {
+			modes |= InvalidMaxLength
+		}
+		if mn, ok := opts.MinLength(); ok && mn > 0 {
+			modes |= InvalidMinLength
+		}
+		if _, ok := opts.Pattern(); ok {
+			modes |= InvalidPattern
+		}
+	case "array":
+		if _, ok := opts.MaxItems(); ok {
+			modes |= InvalidMaxItems
+		}
+		if mn, ok := opts.MinItems(); ok && mn > 0 {
+			modes |= InvalidMinItems
+		}
+		if mx, ok := opts.MaxItems(); opts.UniqueItems() && (!ok || mx > 1) {
+			modes |= InvalidUniqueItems
+		}
+		if items, err := opts.Items(); err == nil && items != nil {
+			modes |= itemModes(items)
+		}
+	case "object":
+		props, err := properties(opts)
+		if err != nil {
+			return modes
+		}
+		for _, prop := range props {
+			if prop.Required() {
+				modes |= InvalidRequired
+			}
+			modes |= delegatedModes(prop)
+		}
+		return modes
+	}
+
+	if opts.Required() {
+		modes |= InvalidRequired
+	}
+	return modes
+}
+
+// itemModes returns the validations the members of a collection can violate,
+// members can only violate required when they are objects.
+func itemModes(items GeneratorOpts) StubMode {
+	modes := violable(items)
+	if items.Type() != "object" {
+		modes &^= InvalidRequired
+	}
+	return modes
+}
+
+// delegatedModes returns the validations the value of an object property can violate on behalf of the object
+func delegatedModes(prop GeneratorOpts) StubMode {
+	modes := violable(prop)
+	if prop.Type() != "object" && prop.Type() != "array" {
+		// a missing scalar property is handled by the object itself
+		modes &^= InvalidRequired
+	}
+	return modes
+}
+
+// conform generates a value which violates exactly the validations selected by the mode of the options
+func (g *generators) conform(opts GeneratorOpts, datagen ValueGenerator) (interface{}, error) {
+	mode := opts.Mode()
+	if enm, ok := opts.Enum(); ok && !mode.Has(InvalidEnum) {
+		return g.enumMember(opts, enm)
+	}
+
+	switch opts.Type() {
+	case "integer", "number":
+		if mode.Has(InvalidRequired) {
+			return requiredViolation(opts)
+		}
+		return g.numeric(opts)
+	case "string":
+		if mode.Has(InvalidRequired) {
+			return requiredViolation(opts)
+		}
+		return g.text(opts, datagen)
+	case "array":
+		if items, err := opts.Items(); mode.Has(InvalidRequired) && (err != nil || items == nil || !itemModes(items).Has(InvalidRequired)) {
+			return requiredViolation(opts)
+		}
+		return g.composite(opts, g.array)
+	case "object":
+		return g.composite(opts, g.object)
+	default:
+		if mode.Has(InvalidRequired) {
+			return requiredViolation(opts)
+		}
+		return g.retry(opts, datagen)
+	}
+}
+
+// describeMode describes the validity of a value for error messages
+func describeMode(mode StubMode) string {
+	if mode == Valid {
+		return "valid"
+	}
+	return "invalid for " + mode.String()
+}
+
+// requiredViolation returns a nil value for a required value, which can't be combined with other violations
+func requiredViolation(opts GeneratorOpts) (interface{}, error) {
+	if mode := opts.Mode() &^ InvalidRequired; mode != Valid {
+		return nil, fmt.Errorf("can't generate a value for [%s] which is invalid for required and %s", opts.FieldName(), mode)
+	}
+	return nil, nil
+}
+
+// enumMember picks a member of the enum which violates exactly the validations selected by the mode.
+// Numeric args of the options are the weights of the members, in the order of the enum.
+func (g *generators) enumMember(opts GeneratorOpts, enm []interface{}) (interface{}, error) {
+	weights, err := enumWeights(opts, enm)
+	if err != nil {
+		return nil, err
+	}
+
+	var candidates []interface{}
+	var candidateWeights []float64
+	var total float64
+	for i, v := range enm {
+		v = enumValue(opts, v)
+		if violations(opts, v) == opts.Mode() && weights[i] > 0 {
+			candidates = append(candidates, v)
+			candidateWeights = append(candidateWeights, weights[i])
+			total += weights[i]
+		}
+	}
+	if len(candidates) == 0 {
+		return nil, fmt.Errorf("no enum value for [%s] is %s", opts.FieldName(), describeMode(opts.Mode()))
+	}
+
+	pick := g.rand.Float64() * total
+	for i, weight := range candidateWeights {
+		if pick < weight {
+			return candidates[i], nil
+		}
+		pick -= weight
+	}
+	return candidates[len(candidates)-1], nil
+}
+
+// enumWeights returns the weight of each member of the enum, the args of the options are the weights when present
+func enumWeights(opts GeneratorOpts, enm []interface{}) ([]float64, error) {
+	weights := make([]float64, len(enm))
+	args := opts.Args()
+	if len(args) == 0 {
+		for i := range weights {
+			weights[i] = 1
+		}
+		return weights, nil
+	}
+
+	if len(args) != len(enm) {
+		return nil, fmt.Errorf("the args for [%s] need a weight for each of the %d enum values, got %d", opts.FieldName(), len(enm), len(args))
+	}
+	for i, arg := range args {
+		weight, ok := toFloat64(arg)
+		if !ok || weight < 0 {
+			return nil, fmt.Errorf("the weight %v for enum value %v of [%s] isn't a positive number", arg, enm[i], opts.FieldName())
+		}
+		weights[i] = weight
+	}
+	return weights, nil
+}
+
+// enumValue converts a numeric enum member to the go type for the type and format of the options,
+// numbers in a document are decoded as float64 whatever their type
+func enumValue(opts GeneratorOpts, value interface{}) interface{} {
+	if opts.Type() != "integer" && opts.Type() != "number" {
+		return value
+	}
+	if num, ok := toFloat64(value); ok {
+		return numericValue(numericFormat(opts), num)
+	}
+	return value
+}
+
+// retry generates values until a value violates exactly the validations selected by the mode
+func (g *generators) retry(opts GeneratorOpts, datagen ValueGenerator) (interface{}, error) {
+	for attempt := 0; attempt < maxAttempts; attempt++ {
+		value, err := datagen(withMode(opts, Valid))
+		if err != nil {
+			return nil, err
+		}
+		if violations(opts, value) == opts.Mode() {
+			return value, nil
+		}
+	}
+	return nil, fmt.Errorf("unable to generate a value for [%s] which is %s after %d attempts", opts.FieldName(), describeMode(opts.Mode()), maxAttempts)
+}
+
+// composite generates a collection or an object, the composite generator takes care of its own validations
+// and of the validations it delegates to its members. A violation of the enum is retried.
+func (g *generators) composite(opts GeneratorOpts, datagen ValueGenerator) (interface{}, error) {
+	enm, _ := opts.Enum()
+	for attempt := 0; attempt < maxAttempts; attempt++ {
+		value, err := datagen(opts)
+		if err != nil {
+			return nil, err
+		}
+		if !opts.Mode().Has(InvalidEnum) || !enumContains(enm, value) {
+			return value, nil
+		}
+	}
+	return nil, fmt.Errorf("unable to generate a value for [%s] which is %s after %d attempts", opts.FieldName(), describeMode(opts.Mode()), maxAttempts)
+}
+
+// distributeModes assigns each validation selected by the mode to a property of an object which can violate it,
+// preferring properties which don't have a violation assigned yet.
+// When the object has required properties a violation of required omits one of them.
+func (g *generators) distributeModes(opts GeneratorOpts, props []GeneratorOpts, mode StubMode) (map[string]StubMode, string, error) {
+	var required []string
+	for _, prop := range props {
+		if prop.Required() {
+			required = append(required, prop.FieldName())
+		}
+	}
+	omitRequired := mode.Has(InvalidRequired) && len(required) > 0
+	if omitRequired {
+		mode &^= InvalidRequired
+	}
+
+	targets := make(map[string]StubMode)
+	for _, m := range validationModes {
+		if !mode.Has(m) {
+			continue
+		}
+		var candidates, unassigned []string
+		for _, prop := range props {
+			if delegatedModes(prop).Has(m) {
+				candidates = append(candidates, prop.FieldName())
+				if _, ok := targets[prop.FieldName()]; !ok {
+					unassigned = append(unassigned, prop.FieldName())
+				}
+			}
+		}
+		if len(unassigned) > 0 {
+			candidates = unassigned
+		}
+		if len(candidates) == 0 {
+			return nil, "", fmt.Errorf("no property of [%s] can be invalid for %s", opts.FieldName(), m)
+		}
+		targets[candidates[g.rand.Intn(len(candidates))]] |= m
+	}
+
+	if !omitRequired {
+		return targets, "", nil
+	}
+	var candidates []string
+	for _, name := range required {
+		if _, ok := targets[name]; !ok {
+			candidates = append(candidates, name)
+		}
+	}
+	if len(candidates) == 0 {
+		return nil, "", fmt.Errorf("no required property of [%s] can be omitted", opts.FieldName())
+	}
+	return targets, candidates[g.rand.Intn(len(candidates))], nil
+}
+
+// splitModes returns the individual validations selected by the mode
+func splitModes(mode StubMode) []StubMode {
+	var modes []StubMode
+	for _, m := range validationModes {
+		if mode.Has(m) {
+			modes = append(modes, m)
+		}
+	}
+	return modes
+}
+
+// numeric generates a number which violates exactly the numeric validations selected by the mode,
+// in the format of the options
+func (g *generators) numeric(opts GeneratorOpts) (interface{}, error) {
+	return g.number(opts, numericFormat(opts))
+}
+
+// number generates a number in a numeric format which violates exactly the numeric validations selected by the mode
+func (g *generators) number(opts GeneratorOpts, format string) (interface{}, error) {
+	mode := opts.Mode()
+	integer := format == "int32" || format == "int64"
+	lo, hi, err := numericRange(opts, format)
+	if err != nil {
+		return nil, err
+	}
+
+	for attempt := 0; attempt < maxAttempts; attempt++ {
+		var value float64
+		mo, hasMultiple := opts.MultipleOf()
+		switch {
+		case hasMultiple && mo > 0 && !mode.Has(InvalidMultipleOf):
+			value, err = g.randomMultiple(lo, hi, mo, integer)
+			if err != nil {
+				return nil, fmt.Errorf("no %s for [%s] which is %s %s", format, opts.FieldName(), describeMode(mode), describeBounds(opts))
+			}
+		case integer:
+			ilo, ihi := math.Ceil(lo), math.Floor(hi)
+			if ilo > ihi {
+				return nil, fmt.Errorf("no %s for [%s] which is %s %s", format, opts.FieldName(), describeMode(mode), describeBounds(opts))
+			}
+			value = float64(g.randomInt64(int64(ilo), int64(ihi)))
+		default:
+			// interpolated so the range of the format doesn't overflow
+			r := g.rand.Float64()
+			value = lo*(1-r) + hi*r
+		}
+
+		result := numericValue(format, value)
+		if violations(opts, result) == mode {
+			return result, nil
+		}
+	}
+	return nil, fmt.Errorf("unable to generate a %s for [%s] which is %s after %d attempts", format, opts.FieldName(), describeMode(mode), maxAttempts)
+}
+
+// formatRanges are the inclusive ranges of the numbers a numeric format can represent
+var formatRanges = map[string][2]float64{
+	"int32": {math.MinInt32, math.MaxInt32},
+	// the largest float below 2^63, which still converts to an int64
+	"int64":  {math.MinInt64, math.Nextafter(math.MaxInt64, 0)},
+	"float":  {-math.MaxFloat32, math.MaxFloat32},
+	"double": {-math.MaxFloat64, math.MaxFloat64},
+}
+
+// numericFormat returns the format of the numbers for the options, integers are int64 and numbers are double by default
+func numericFormat(opts GeneratorOpts) string {
+	switch {
+	case opts.Type() == "integer" && opts.Format() == "int32":
+		return "int32"
+	case opts.Type() == "integer":
+		return "int64"
+	case opts.Format() == "float":
+		return "float"
+	default:
+		return "double"
+	}
+}
+
+// numericRange returns the inclusive range a number is picked from, within the range of the numeric format
+func numericRange(opts GeneratorOpts, format string) (float64, float64, error) {
+	mode := opts.Mode()
+	max, exclMax, hasMax := opts.Maximum()
+	min, exclMin, hasMin := opts.Minimum()
+
+	var lo, hi float64
+	switch {
+	case mode.Has(InvalidMaximum) && mode.Has(InvalidMinimum):
+		// only possible when the minimum is larger than the maximum
+		lo, hi = above(max, !exclMax), below(min, !exclMin)
+	case mode.Has(InvalidMaximum):
+		lo = above(max, !exclMax)
+		hi = lo + defaultNumericSpread
+	case mode.Has(InvalidMinimum):
+		hi = below(min, !exclMin)
+		lo = hi - defaultNumericSpread
+	case hasMin && hasMax:
+		lo, hi = above(min, exclMin), below(max, exclMax)
+	case hasMin:
+		lo = above(min, exclMin)
+		hi = lo + defaultNumericSpread
+	case hasMax:
+		hi = below(max, exclMax)
+		lo = hi - defaultNumericSpread
+	default:
+		lo, hi = 0, defaultNumericSpread
+	}
+
+	if bounds, ok := formatRanges[format]; ok {
+		lo, hi = math.Max(lo, bounds[0]), math.Min(hi, bounds[1])
+	}
+	if lo > hi || math.IsNaN(lo) || math.IsNaN(hi) {
+		return 0, 0, fmt.Errorf("no %s for [%s] which is %s %s", format, opts.FieldName(), describeMode(mode), describeBounds(opts))
+	}
+	return lo, hi, nil
+}
+
+// describeBounds describes the numeric validations of the options for error messages
+func describeBounds(opts GeneratorOpts) string {
+	var bounds []string
+	if min, excl, ok := opts.Minimum(); ok {
+		bounds = append(bounds, describeBound("minimum", min, excl))
+	}
+	if max, excl, ok := opts.Maximum(); ok {
+		bounds = append(bounds, describeBound("maximum", max, excl))
+	}
+	if mo, ok := opts.MultipleOf(); ok {
+		bounds = append(bounds, fmt.Sprintf("multipleOf %v", mo))
+	}
+	if len(bounds) == 0 {
+		return "without bounds"
+	}
+	return "with " + strings.Join(bounds, ", ")
+}
+
+func describeBound(name string, bound float64, exclusive bool) string {
+	if exclusive {
+		return fmt.Sprintf("exclusive %s %v", name, bound)
+	}
+	return fmt.Sprintf("%s %v", name, bound)
+}
+
+// above returns the bound itself or the next number above the bound when the bound is excluded
+func above(bound float64, exclusive bool) float64 {
+	if exclusive {
+		return math.Nextafter(bound, math.Inf(1))
+	}
+	return bound
+}
+
+// below returns the bound itself or the next number below the bound when the bound is excluded
+func below(bound float64, exclusive bool) float64 {
+	if exclusive {
+		return math.Nextafter(bound, math.Inf(-1))
+	}
+	return bound
+}
+
+// randomMultiple picks a multiple of mo between lo and hi, rounded to the precision of mo
+func (g *generators) randomMultiple(lo, hi, mo float64, integer bool) (float64, error) {
+	step := mo
+	if integer {
+		// the smallest multiple of mo which is an integer
+		n := 1
+		for ; n <= defaultNumericSpread && !isMultipleOf(float64(n)*mo, 1); n++ {
+		}
+		if n > defaultNumericSpread {
+			return 0, fmt.Errorf("no integer multiple of %v", mo)
+		}
+		step = math.Round(float64(n) * mo)
+	}
+
+	klo, khi := math.Ceil(lo/step), math.Floor(hi/step)
+	if klo > khi {
+		return 0, fmt.Errorf("no multiple of %v between %v and %v", mo, lo, hi)
+	}
+	k := g.randomInt64(int64(klo), int64(khi))
+	return roundTo(float64(k)*step, step), nil
+}
+
+// roundTo rounds a value to the number of decimals of the precision, so that 3 * 0.1 becomes 0.3
+func roundTo(value, precision float64) float64 {
+	str := strconv.FormatFloat(precision, 'f', -1, 64)
+	decimals := 0
+	for i := len(str) - 1; i >= 0 && str[i] != '.'; i-- {
+		decimals++
+	}
+	if decimals == len(str) {
+		return math.Round(value)
+	}
+	rounded, err := strconv.ParseFloat(strconv.FormatFloat(value, 'f', decimals, 64), 64)
+	if err != nil {
+		return value
+	}
+	return rounded
+}
+
+// isMultipleOf returns true when value is a multiple of factor, allowing for floating point errors
+func isMultipleOf(value, factor float64) bool {
+	mult := value / factor
+	if factor < 1 {
+		mult = 1 / factor * value
+	}
+	return math.Abs(mult-math.Round(mult)) < 1e-9
+}
+
+// numericValue converts a number to the go type for the numeric format
+func numericValue(format string, value float64) interface{} {
+	switch format {
+	case "int32":
+		return int32(value)
+	case "int64":
+		return int64(value)
+	case "float":
+		return float32(value)
+	default:
+		return value
+	}
+}
+
+// text generates a string which violates exactly the string validations selected by the mode.
+// A string for a pattern is generated from the pattern, otherwise the value generator's string is padded or truncated.
+// A string in a format is generated again until its length fits, padding or truncating it would break the format.
+func (g *generators) text(opts GeneratorOpts, datagen ValueGenerator) (interface{}, error) {
+	mode := opts.Mode()
+	lo, hi, err := lengthRange(opts)
+	if err != nil {
+		return nil, err
+	}
+	pattern, hasPattern := opts.Pattern()
+	formatted := strfmt.Default.ContainsName(opts.Format())
+
+	// the number of repetitions for the unbounded repeats in the pattern,
+	// adjusted by how much a generated string misses the length range
+	minRepeat, maxRepeat := lo, hi
+	for attempt := 0; attempt < maxAttempts; attempt++ {
+		var value string
+		switch {
+		case hasPattern && !mode.Has(InvalidPattern):
+			value, err = g.generateWithLength(pattern, minRepeat, maxRepeat)
+			if err != nil {
+				return nil, err
+			}
+			if length := utf8.RuneCountInString(value); length > hi {
+				maxRepeat -= length - hi
+				if maxRepeat < 0 {
+					maxRepeat = 0
+				}
+				if minRepeat > maxRepeat {
+					minRepeat = maxRepeat
+				}
+			} else if length < lo {
+				minRepeat += lo - length
+				if maxRepeat < minRepeat {
+					maxRepeat = minRepeat
+				}
+			}
+		case hasPattern:
+			value = g.randomChars(lo+g.rand.Intn(hi-lo+1), invalidPatternChars)
+		default:
+			v, err := datagen(withMode(opts, Valid))
+			if err != nil {
+				return nil, err
+			}
+			value = fmt.Sprint(v)
+			if !formatted {
+				value = g.fitLength(value, lo, hi)
+			}
+		}
+
+		if violations(opts, value) == mode {
+			return value, nil
+		}
+	}
+	if formatted {
+		return nil, fmt.Errorf("unable to generate a %s string for [%s] which is %s after %d attempts", opts.Format(), opts.FieldName(), describeMode(mode), maxAttempts)
+	}
+	return nil, fmt.Errorf("unable to generate a string for [%s] which is %s after %d attempts", opts.FieldName(), describeMode(mode), maxAttempts)
+}
+
+// lengthRange returns the inclusive range for the length of a string
+func lengthRange(opts GeneratorOpts) (int, int, error) {
+	mode := opts.Mode()
+	maxLen, hasMax := opts.MaxLength()
+	minLen, hasMin := opts.MinLength()
+
+	var lo, hi int
+	switch {
+	case mode.Has(InvalidMaxLength) && mode.Has(InvalidMinLength):
+		lo, hi = int(maxLen)+1, int(minLen)-1
+	case mode.Has(InvalidMaxLength):
+		lo = int(maxLen) + 1
+		hi = lo + defaultLengthSpread
+	case mode.Has(InvalidMinLength):
+		lo, hi = 0, int(minLen)-1
+	default:
+		if hasMin {
+			lo = int(minLen)
+		}
+		hi = lo + defaultLengthSpread
+		if hasMax {
+			hi = int(maxLen)
+		}
+	}
+
+	if lo < 0 || lo > hi {
+		return 0, 0, fmt.Errorf("no string length for [%s] between %d and %d", opts.FieldName(), lo, hi)
+	}
+	return lo, hi, nil
+}
+
+// generateWithLength generates a string for a pattern, using the length range as a hint for the repetitions
+func (g *generators) generateWithLength(pattern string, lo, hi int) (string, error) {
+	args := &regen.GeneratorArgs{
+		Flags:                   syntaxFlags,
+		RngSource:               g.source(),
+		MinUnboundedRepeatCount: uint(lo),
+		MaxUnboundedRepeatCount: uint(hi),
+	}
+	if args.MaxUnboundedRepeatCount < 1 {
+		args.MaxUnboundedRepeatCount = 1
+	}
+	if args.MinUnboundedRepeatCount > args.MaxUnboundedRepeatCount {
+		args.MinUnboundedRepeatCount = args.MaxUnboundedRepeatCount
+	}
+	gen, err := regen.NewGenerator(pattern, args)
+	if err != nil {
+		return "", err
+	}
+	return gen.Generate(), nil
+}
+
+// fitLength pads or truncates a string so its length is between lo and hi
+func (g *generators) fitLength(str string, lo, hi int) string {
+	runes := []rune(str)
+	if len(runes) > hi {
+		runes = runes[:lo+g.rand.Intn(hi-lo+1)]
+	}
+	if len(runes) < lo {
+		runes = append(runes, []rune(g.randomChars(lo-len(runes), invalidPatternChars[:62]))...)
+	}
+	return string(runes)
+}
+
+func (g *generators) randomChars(n int, chars string) string {
+	result := make([]byte, n)
+	for i := range result {
+		result[i] = chars[g.rand.Intn(len(chars))]
+	}
+	return string(result)
+}
+
+// violations returns the validations a value violates for the options
+func violations(opts GeneratorOpts, value interface{}) StubMode {
+	var modes StubMode
+	if value == nil {
+		if opts.Required() && opts.Type() != "null" {
+			modes |= InvalidRequired
+		}
+		return modes
+	}
+	if enm, ok := opts.Enum(); ok && !enumContains(enm, value) {
+		modes |= InvalidEnum
+	}
+
+	if num, ok := toFloat64(value); ok {
+		if max, excl, ok := opts.Maximum(); ok && (num > max || excl && num == max) {
+			modes |= InvalidMaximum
+		}
+		if min, excl, ok := opts.Minimum(); ok && (num < min || excl && num == min) {
+			modes |= InvalidMinimum
+		}
+		if mo, ok := opts.MultipleOf(); ok && mo > 0 && !isMultipleOf(num, mo) {
+			modes |= InvalidMultipleOf
+		}
+		return modes
+	}
+
+	switch val := value.(type) {
+	case string:
+		length := int64(utf8.RuneCountInString(val))
+		if mx, ok := opts.MaxLength(); ok && length > mx {
+			modes |= InvalidMaxLength
+		}
+		if mn, ok := opts.MinLength(); ok && length < mn {
+			modes |= InvalidMinLength
+		}
+		if pattern, ok := opts.Pattern(); ok {
+			if rx, err := regexp.Compile(pattern); err == nil && !rx.MatchString(val) {
+				modes |= InvalidPattern
+			}
+		}
+		if patterned, ok := opts.(patternedOpts); ok {
+			for _, pattern := range patterned.patterns() {
+				if rx, err := regexp.Compile(pattern); err == nil && !rx.MatchString(val) {
+					modes |= InvalidPattern
+				}
+			}
+		}
+	case []interface{}:
+		length := int64(len(val))
+		if mx, ok := opts.MaxItems(); ok && length > mx {
+			modes |= InvalidMaxItems
+		}
+		if mn, ok := opts.MinItems(); ok && length < mn {
+			modes |= InvalidMinItems
+		}
+		if opts.UniqueItems() {
+			for i := range val {
+				if containsValue(val[:i], val[i]) {
+					modes |= InvalidUniqueItems
+					break
+				}
+			}
+		}
+	case map[string]interface{}:
+		if props, err := properties(opts); err == nil {
+			for _, prop := range props {
+				if _, ok := val[prop.FieldName()]; !ok && prop.Required() {
+					modes |= InvalidRequired
+				}
+			}
+		}
+	}
+	return modes
+}
+
+// enumContains returns true when the value is a member of the enum, numbers are compared by value
+func enumContains(enm []interface{}, value interface{}) bool {
+	num, isNum := toFloat64(value)
+	for _, v := range enm {
+		if isNum {
+			if n, ok := toFloat64(v); ok && n == num {
+				return true
+			}
+			continue
+		}
+		if reflect.DeepEqual(v, value) {
+			return true
+		}
+	}
+	return false
+}
+
+func toFloat64(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case int:
+		return float64(v), true
+	case int32:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case float32:
+		return float64(v), true
+	case float64:
+		return v, true
+	default:
+		return 0, false
+	}
+}
@@ -0,0 +1,268 @@
+package stubs
+
+import (
+	"testing"
+
+	"github.com/go-openapi/spec"
+	"github.com/go-openapi/strfmt"
+	"github.com/go-openapi/swag"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStubMode_String(t *testing.T) {
+	assert.Equal(t, "valid", Valid.String())
+	assert.Equal(t, "maximum", InvalidMaximum.String())
+	assert.Equal(t, "minLength|pattern", (InvalidPattern | InvalidMinLength).String())
+}
+
+func TestParseStubMode(t *testing.T) {
+	for _, mode := range []StubMode{Valid, Invalid, InvalidMaximum, InvalidPattern | InvalidMinLength, Invalid | InvalidEnum} {
+		parsed, err := ParseStubMode(mode.String())
+		if assert.NoError(t, err) {
+			assert.Equal(t, mode, parsed)
+		}
+	}
+	mode, err := ParseStubMode(" MaxLength | uniqueitems")
+	if assert.NoError(t, err) {
+		assert.Equal(t, InvalidMaxLength|InvalidUniqueItems, mode)
+	}
+	_, err = ParseStubMode("maxLength|lenient")
+	assert.Error(t, err)
+	_, err = ParseStubMode("")
+	assert.Error(t, err)
+}
+
+func TestGenerators_InvalidNumbers(t *testing.T) {
+	gen, err := newGenerator("", nil)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	schema := spec.Int64Property().WithMinimum(10, false).WithMaximum(100, true).WithMultipleOf(5)
+	for _, mode := range []StubMode{InvalidMaximum, InvalidMinimum, InvalidMultipleOf, InvalidMaximum | InvalidMultipleOf} {
+		opts := &schemaOpts{fieldName: "count", schema: schema, mode: mode}
+		fn, found := gen.For(opts)
+		if assert.True(t, found) {
+			for i := 0; i < 20; i++ {
+				res, err := fn(opts)
+				if assert.NoError(t, err, "for %s", mode) && assert.IsType(t, int64(0), res) {
+					assert.Equal(t, mode, violations(opts, res), "for %s with value %v", mode, res)
+					if mode.Has(InvalidMaximum) {
+						assert.True(t, res.(int64) >= 100)
+					}
+				}
+			}
+		}
+	}
+
+	// an exclusive maximum is violated by the maximum itself
+	schema = spec.Int32Property().WithMaximum(5, true).WithMinimum(5, false)
+	opts := &schemaOpts{schema: schema, mode: InvalidMaximum}
+	fn, _ := gen.For(opts)
+	res, err := fn(opts)
+	if assert.NoError(t, err) {
+		assert.True(t, res.(int32) >= 5)
+	}
+	opts.mode = Valid
+	_, err = fn(opts)
+	assert.Error(t, err)
+
+	// every integer is a multiple of 0.5
+	opts = &schemaOpts{schema: spec.Int64Property().WithMultipleOf(0.5), mode: InvalidMultipleOf}
+	fn, _ = gen.For(opts)
+	_, err = fn(opts)
+	assert.Error(t, err)
+
+	// a number without a maximum can't be too large
+	opts = &schemaOpts{schema: spec.Float64Property(), mode: InvalidMaximum}
+	fn, _ = gen.For(opts)
+	_, err = fn(opts)
+	assert.Error(t, err)
+}
+
+func TestGenerators_InvalidStrings(t *testing.T) {
+	gen, err := newGenerator("", nil)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	schema := spec.StringProperty().WithMinLength(3).WithMaxLength(8).WithPattern(`^[a-z]+$`)
+	for _, mode := range []StubMode{Valid, InvalidMaxLength, InvalidMinLength, InvalidPattern, InvalidPattern | InvalidMaxLength} {
+		opts := &schemaOpts{fieldName: "city", schema: schema, mode: mode}
+		fn, found := gen.For(opts)
+		if assert.True(t, found) && mode != Valid {
+			for i := 0; i < 20; i++ {
+				res, err := fn(opts)
+				if assert.NoError(t, err, "for %s", mode) {
+					assert.Equal(t, mode, violations(opts, res), "for %s with value %q", mode, res)
+				}
+			}
+		}
+	}
+
+	// semantic values are padded and truncated to violate only the length
+	schema = spec.StringProperty().WithMinLength(20).WithMaxLength(30)
+	opts := &schemaOpts{fieldName: "city", schema: schema, mode: InvalidMinLength}
+	fn, _ := gen.For(opts)
+	res, err := fn(opts)
+	if assert.NoError(t, err) {
+		assert.True(t, len(res.(string)) < 20)
+	}
+
+	// values in a format are generated again rather than cut or padded out of their format
+	opts = &schemaOpts{fieldName: "ref", schema: spec.StrFmtProperty("uuid").WithMaxLength(5)}
+	fn, _ = gen.For(opts)
+	_, err = fn(opts)
+	assert.Error(t, err)
+	opts.mode = InvalidMaxLength
+	res, err = fn(opts)
+	if assert.NoError(t, err) {
+		assert.True(t, strfmt.IsUUID(res.(string)), res)
+	}
+	opts = &schemaOpts{fieldName: "contact", schema: spec.StrFmtProperty("email").WithMaxLength(20)}
+	fn, _ = gen.For(opts)
+	for i := 0; i < 20; i++ {
+		res, err := fn(opts)
+		if assert.NoError(t, err) {
+			assert.True(t, len(res.(string)) <= 20, res)
+			assert.True(t, strfmt.IsEmail(res.(string)), res)
+		}
+	}
+
+	// a required parameter is omitted
+	param := spec.QueryParam("q").Typed("string", "").AsRequired()
+	popts, err := paramGenOpts("", param)
+	if assert.NoError(t, err) {
+		popts.mode = InvalidRequired
+		fn, _ = gen.For(popts)
+		res, err = fn(popts)
+		if assert.NoError(t, err) {
+			assert.Nil(t, res)
+		}
+	}
+}
+
+func TestGenerators_InvalidEnum(t *testing.T) {
+	gen, err := newGenerator("", nil)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	enm := []interface{}{"available", "pending", "sold"}
+	opts := &schemaOpts{fieldName: "status", schema: spec.StringProperty().WithEnum(enm...), mode: InvalidEnum}
+	fn, _ := gen.For(opts)
+	for i := 0; i < 20; i++ {
+		res, err := fn(opts)
+		if assert.NoError(t, err) {
+			assert.IsType(t, "", res)
+			assert.NotContains(t, enm, res)
+		}
+	}
+
+	// an enum value which violates the max length
+	opts = &schemaOpts{fieldName: "status", schema: spec.StringProperty().WithEnum(enm...).WithMaxLength(4), mode: InvalidMaxLength}
+	fn, _ = gen.For(opts)
+	res, err := fn(opts)
+	if assert.NoError(t, err) {
+		assert.Contains(t, enm, res)
+	}
+
+	opts = &schemaOpts{schema: spec.BoolProperty().WithEnum(true), mode: InvalidEnum}
+	fn, _ = gen.For(opts)
+	res, err = fn(opts)
+	if assert.NoError(t, err) {
+		assert.Equal(t, false, res)
+	}
+}
+
+func TestGenerators_InvalidCollections(t *testing.T) {
+	gen, err := newGenerator("", nil)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	schema := spec.ArrayProperty(spec.Int64Property().WithMaximum(10, false)).WithMinItems(2).WithMaxItems(4).UniqueValues()
+	for _, mode := range []StubMode{InvalidMaxItems, InvalidMinItems, InvalidUniqueItems, InvalidMaximum, InvalidUniqueItems | InvalidMaximum} {
+		opts := &schemaOpts{fieldName: "scores", schema: schema, mode: mode}
+		fn, _ := gen.For(opts)
+		for i := 0; i < 20; i++ {
+			res, err := fn(opts)
+			if assert.NoError(t, err, "for %s", mode) && assert.IsType(t, []interface{}{}, res) {
+				items := res.([]interface{})
+				assert.Equal(t, mode&collectionModes, violations(opts, items), "for %s with value %v", mode, items)
+
+				var above int
+				for _, item := range items {
+					if item.(int64) > 10 {
+						above++
+					}
+				}
+				if mode.Has(InvalidMaximum) {
+					assert.True(t, above > 0)
+				} else {
+					assert.Equal(t, 0, above)
+				}
+			}
+		}
+	}
+}
+
+func TestGenerators_InvalidObjects(t *testing.T) {
+	gen, err := newGenerator("", nil)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	schema := new(spec.Schema).
+		Typed("object", "").
+		WithRequired("id", "name").
+		SetProperty("id", *spec.Int64Property().WithMinimum(1, false)).
+		SetProperty("name", *spec.StringProperty().WithMaxLength(10)).
+		SetProperty("tag", *spec.StringProperty().WithPattern(`^[a-z]+$`))
+
+	opts := &schemaOpts{fieldName: "Pet", schema: schema, required: true, mode: InvalidRequired}
+	fn, _ := gen.For(opts)
+	for i := 0; i < 20; i++ {
+		res, err := fn(opts)
+		if assert.NoError(t, err) {
+			pet := res.(map[string]interface{})
+			_, hasID := pet["id"]
+			_, hasName := pet["name"]
+			assert.True(t, hasID != hasName, "expected exactly one required property to be omitted from %v", pet)
+		}
+	}
+
+	opts.mode = InvalidPattern | InvalidMinimum
+	for i := 0; i < 20; i++ {
+		res, err := fn(opts)
+		if assert.NoError(t, err) {
+			pet := res.(map[string]interface{})
+			if assert.Contains(t, pet, "tag") {
+				assert.NotRegexp(t, `^[a-z]+$`, pet["tag"])
+			}
+			assert.True(t, pet["id"].(int64) < 1)
+			assert.True(t, len(pet["name"].(string)) <= 10)
+		}
+	}
+
+	opts.mode = Invalid
+	for i := 0; i < 20; i++ {
+		_, err := fn(opts)
+		assert.NoError(t, err)
+	}
+
+	opts.mode = InvalidMaxItems
+	_, err = fn(opts)
+	assert.Error(t, err)
+
+	opts = &schemaOpts{schema: spec.StringProperty(), mode: Invalid}
+	fn, _ = gen.For(opts)
+	_, err = fn(opts)
+	assert.Error(t, err)
+
+	opts = &schemaOpts{schema: spec.StringProperty().WithMinLength(1), mode: InvalidMinLength}
+	opts.schema.MaxLength = swag.Int64(0)
+	fn, _ = gen.For(opts)
+	_, err = fn(opts)
+	assert.NoError(t, err)
+}
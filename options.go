@@ -1,6 +1,13 @@
 package stubs
 
 import (
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+	"strconv"
+	"strings"
+
 	"github.com/go-openapi/spec"
 	"github.com/go-openapi/swag"
 	"github.com/mitchellh/mapstructure"
@@ -60,13 +67,142 @@ type GeneratorOpts interface {
 	// Enum a list of acceptable values for a value, returns value, defined
 	Enum() ([]interface{}, bool)
 
-	// Items options for the members of a collection
+	// Items options for the members of a collection, returns nil when the collection doesn't define its items
 	Items() (GeneratorOpts, error)
 
 	// Required when true the property can't be nil
 	Required() bool
 }
 
+// SourceOpts are generator options which give value generators the random source and the other value generators.
+// The options a Generator passes to value generators implement it, value generators check for it with a type assertion.
+type SourceOpts interface {
+	// Rand the source for random data, value generators use it so the same seed generates the same data
+	Rand() *rand.Rand
+
+	// Generator returns the registered or built-in value generator for a name, so value generators can be composed
+	Generator(name string) (ValueGenerator, bool)
+}
+
+// objectOpts are generator options for an object with properties
+type objectOpts interface {
+	// Properties options for the properties of an object, ordered by property name.
+	// The field name of each option is the property name.
+	Properties() ([]GeneratorOpts, error)
+}
+
+// properties returns the options for the properties of an object, nil when the options don't have properties
+func properties(opts GeneratorOpts) ([]GeneratorOpts, error) {
+	if object, ok := opts.(objectOpts); ok {
+		return object.Properties()
+	}
+	return nil, nil
+}
+
+// locatedOpts are generator options which know their location in the generated value,
+// so stub modes can be targeted at a location
+type locatedOpts interface {
+	// targeted returns true when a stub mode targets the location of the options or a location within
+	targeted() bool
+
+	// element returns the options for the member at an index of a collection, for the options of the items
+	element(index int) GeneratorOpts
+
+	// elements returns the number of members a collection needs to contain all the targeted locations,
+	// for the options of the items
+	elements() int
+}
+
+// modeTargets are the stub modes for locations in a generated value, keyed by json pointer
+type modeTargets map[string]StubMode
+
+// within returns true when a location at the path or inside the path has a stub mode
+func (m modeTargets) within(path string) bool {
+	for k, v := range m {
+		if v != Valid && (k == path || strings.HasPrefix(k, path+"/")) {
+			return true
+		}
+	}
+	return false
+}
+
+// under returns the stub modes for the locations at the path or inside the path
+func (m modeTargets) under(path string) modeTargets {
+	targets := make(modeTargets)
+	for k, v := range m {
+		if k == path || strings.HasPrefix(k, path+"/") {
+			targets[k] = v
+		}
+	}
+	return targets
+}
+
+// elements returns the number of members a collection at the path needs to contain all its targeted locations
+func (m modeTargets) elements(path string) int {
+	var count int
+	for k, v := range m {
+		if v == Valid || !strings.HasPrefix(k, path+"/") {
+			continue
+		}
+		token := strings.SplitN(strings.TrimPrefix(k, path+"/"), "/", 2)[0]
+		if idx, err := strconv.Atoi(token); err == nil && idx >= count {
+			count = idx + 1
+		}
+	}
+	return count
+}
+
+var pointerEscaper = strings.NewReplacer("~", "~0", "/", "~1")
+
+// nestedOpts are generator options which know the schema references that were followed to reach them,
+// so recursive models stop at the maximum depth
+type nestedOpts interface {
+	// recursion returns an error naming the cycle when the options are nested deeper than the maximum depth
+	recursion() error
+}
+
+// patternedOpts are generator options for a string which has to match more patterns than the pattern of the options
+type patternedOpts interface {
+	patterns() []string
+}
+
+// polymorphicOpts are generator options for a schema with a discriminator,
+// the value is generated for one of the definitions in the document which extend the schema
+type polymorphicOpts interface {
+	// discriminator returns the name of the discriminator property and the name of the type of the schema,
+	// empty when the schema has no discriminator
+	discriminator() (string, string)
+
+	// subtype picks the options of the concrete type to generate, returns nil when the type is already picked
+	subtype() (GeneratorOpts, error)
+}
+
+// composedOpts are generator options for a schema with oneOf, anyOf or not,
+// the value is generated for the schema merged with the picked subschemas
+type composedOpts interface {
+	// composition returns the keyword to generate a value for and the number of its subschemas,
+	// oneOf takes precedence over anyOf, which takes precedence over not. Returns an empty keyword without composition.
+	composition() (string, int)
+
+	// derive returns the options for the schema without the keyword, merged with the subschemas at the indexes
+	derive(keyword string, indexes []int) (GeneratorOpts, error)
+
+	// exclusive returns true when a value for the subschemas at the indexes doesn't validate against
+	// the other subschemas of oneOf, or against the subschema of not
+	exclusive(keyword string, indexes []int, value interface{}) (bool, error)
+
+	// pointer returns the location of the value as json pointer
+	pointer() string
+}
+
+// recursion returns the error for options nested deeper than the maximum depth, nil otherwise
+func recursion(opts GeneratorOpts) error {
+	if nested, ok := opts.(nestedOpts); ok {
+		return nested.recursion()
+	}
+	return nil
+}
+
 func paramGenOpts(key string, param *spec.Parameter) (*simpleOpts, error) {
 	var gopts genOpts
 	if ext, ok := param.Extensions["x-datagen"]; ok {
@@ -152,6 +288,9 @@ type simpleOpts struct {
 	fieldName string
 	required  bool
 	mode      StubMode
+	path      string
+	targets   modeTargets
+	gens      *generators
 }
 
 func (g *simpleOpts) Mode() StubMode {
@@ -209,14 +348,52 @@ func (g *simpleOpts) Format() string {
 	return g.SimpleSchema.Format
 }
 func (g *simpleOpts) Items() (GeneratorOpts, error) {
-	return itemsGenOpts(g.name+".items", g.SimpleSchema.Items)
+	if g.SimpleSchema.Items == nil {
+		return nil, nil
+	}
+	items, err := itemsGenOpts(g.fieldName+".items", g.SimpleSchema.Items)
+	if err != nil {
+		return nil, err
+	}
+	items.path, items.targets, items.gens = g.path, g.targets, g.gens
+	return items, nil
 }
 func (g *simpleOpts) Required() bool {
 	return g.required
 }
+func (g *simpleOpts) Rand() *rand.Rand {
+	return g.gens.random()
+}
+func (g *simpleOpts) Generator(name string) (ValueGenerator, bool) {
+	return g.gens.byName(name)
+}
+func (g *simpleOpts) locate(path string, targets modeTargets) {
+	g.path, g.targets = path, targets
+	g.mode |= targets[path]
+}
+func (g *simpleOpts) targeted() bool {
+	return g.targets.within(g.path)
+}
+func (g *simpleOpts) element(index int) GeneratorOpts {
+	elem := *g
+	elem.locate(g.path+"/"+strconv.Itoa(index), g.targets)
+	return &elem
+}
+func (g *simpleOpts) elements() int {
+	return g.targets.elements(g.path)
+}
 
 type schemaOpts struct {
 	schema *spec.Schema
+	base   string
+	// ref is the reference the schema was found by, concrete is true when it's the picked type for a discriminator
+	ref      string
+	concrete bool
+	// refs are the references followed to reach the schema, cycle is set when the last one recurs too often
+	refs  []string
+	cycle []string
+	// extraPatterns are the patterns a value has to match besides the pattern of the schema
+	extraPatterns []string
 
 	name string
 	args []interface{}
@@ -224,6 +401,9 @@ type schemaOpts struct {
 	fieldName string
 	required  bool
 	mode      StubMode
+	path      string
+	targets   modeTargets
+	gens      *generators
 }
 
 func (s *schemaOpts) Mode() StubMode {
@@ -276,7 +456,7 @@ func (s *schemaOpts) Enum() ([]interface{}, bool) {
 }
 func (s *schemaOpts) Type() string {
 	if len(s.schema.Type) == 0 {
-		return "object"
+		return inferType(s.schema)
 	}
 	return s.schema.Type[0]
 }
@@ -284,8 +464,287 @@ func (s *schemaOpts) Format() string {
 	return s.schema.Format
 }
 func (s *schemaOpts) Items() (GeneratorOpts, error) {
-	return schemaGenOpts(s.fieldName+".items", false, s.schema.Items.Schema)
+	if s.schema.Items == nil || s.schema.Items.Schema == nil {
+		return nil, nil
+	}
+	items, err := s.child(s.fieldName+".items", false, s.schema.Items.Schema)
+	if err != nil {
+		return nil, err
+	}
+	items.path, items.targets = s.path, s.targets
+	return items, nil
+}
+func (s *schemaOpts) Properties() ([]GeneratorOpts, error) {
+	if len(s.schema.Properties) == 0 {
+		return nil, nil
+	}
+
+	names := make([]string, 0, len(s.schema.Properties))
+	for name := range s.schema.Properties {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	props := make([]GeneratorOpts, 0, len(names))
+	for _, name := range names {
+		prop := s.schema.Properties[name]
+		popts, err := s.child(name, swag.ContainsStrings(s.schema.Required, name), &prop)
+		if err != nil {
+			return nil, err
+		}
+		popts.locate(s.path+"/"+pointerEscaper.Replace(name), s.targets)
+		props = append(props, popts)
+	}
+	return props, nil
 }
 func (s *schemaOpts) Required() bool {
 	return s.required
 }
+
+// child creates the options for a schema inside this schema, references in the schema are resolved.
+// A schema which is nested in itself more than the maximum depth gets the cycle of references that lead to it.
+func (s *schemaOpts) child(key string, required bool, schema *spec.Schema) (*schemaOpts, error) {
+	resolved, err := s.gens.resolve(schema, s.base)
+	if err != nil {
+		return nil, err
+	}
+	opts, err := schemaGenOpts(key, required, resolved.schema)
+	if err != nil {
+		return nil, err
+	}
+	opts.base, opts.gens, opts.refs, opts.extraPatterns = resolved.base, s.gens, s.refs, resolved.patterns
+	if resolved.ref == "" {
+		return opts, nil
+	}
+	if err := opts.refGenOpts(schema); err != nil {
+		return nil, err
+	}
+
+	opts.ref = resolved.ref
+	opts.refs = append(s.refs[:len(s.refs):len(s.refs)], resolved.ref)
+	var depth, start int
+	for i, ref := range s.refs {
+		if ref == resolved.ref {
+			depth++
+			start = i
+		}
+	}
+	if depth >= s.gens.maxDepth {
+		opts.cycle = opts.refs[start:]
+	}
+	return opts, nil
+}
+
+// refGenOpts applies the x-datagen extension of a schema with a reference,
+// which takes precedence over the extension of the schema it refers to
+func (s *schemaOpts) refGenOpts(schema *spec.Schema) error {
+	ext, ok := schema.Extensions["x-datagen"]
+	if !ok {
+		return nil
+	}
+	var gopts genOpts
+	if err := mapstructure.WeakDecode(ext, &gopts); err != nil {
+		return err
+	}
+	s.name, s.args = gopts.Name, gopts.Args
+	return nil
+}
+func (s *schemaOpts) discriminator() (string, string) {
+	if s.schema.Discriminator == "" || s.ref == "" {
+		return "", ""
+	}
+	name := s.ref[strings.LastIndex(s.ref, "/")+1:]
+	return s.schema.Discriminator, strings.NewReplacer("~1", "/", "~0", "~").Replace(name)
+}
+
+// subtype picks one of the definitions which extend the schema, string args limit the choice to the named definitions.
+// The schema itself is a candidate when it extends another definition or when nothing extends it.
+func (s *schemaOpts) subtype() (GeneratorOpts, error) {
+	if s.concrete || s.schema.Discriminator == "" || s.ref == "" || s.gens == nil || s.gens.resolver == nil {
+		return nil, nil
+	}
+	_, self := s.discriminator()
+	candidates, extending := s.gens.resolver.subtypes(s.ref)
+	if extending || len(candidates) == 0 {
+		candidates = append(candidates, self)
+	}
+	if len(s.args) > 0 {
+		var named []string
+		for _, arg := range s.args {
+			if name, ok := arg.(string); ok && swag.ContainsStrings(candidates, name) {
+				named = append(named, name)
+			}
+		}
+		if len(named) == 0 {
+			return nil, fmt.Errorf("no type named %v extends %s for [%s]", s.args, s.ref, s.fieldName)
+		}
+		candidates = named
+	}
+
+	picked := candidates[s.gens.rand.Intn(len(candidates))]
+	if picked == self {
+		concrete := *s
+		concrete.concrete = true
+		return &concrete, nil
+	}
+	root := *s
+	root.base = ""
+	sub, err := root.child(s.fieldName, s.required, spec.RefSchema(definitionRef(picked)))
+	if err != nil {
+		return nil, err
+	}
+	sub.path, sub.targets, sub.mode, sub.concrete = s.path, s.targets, s.mode, true
+	return sub, nil
+}
+func (s *schemaOpts) composition() (string, int) {
+	switch {
+	case len(s.schema.OneOf) > 0:
+		return "oneOf", len(s.schema.OneOf)
+	case len(s.schema.AnyOf) > 0:
+		return "anyOf", len(s.schema.AnyOf)
+	case s.schema.Not != nil:
+		return "not", 1
+	default:
+		return "", 0
+	}
+}
+func (s *schemaOpts) derive(keyword string, indexes []int) (GeneratorOpts, error) {
+	outer := *s.schema
+	var branches []spec.Schema
+	switch keyword {
+	case "oneOf":
+		outer.OneOf = nil
+		for _, i := range indexes {
+			branches = append(branches, s.schema.OneOf[i])
+		}
+	case "anyOf":
+		outer.AnyOf = nil
+		for _, i := range indexes {
+			branches = append(branches, s.schema.AnyOf[i])
+		}
+	case "not":
+		outer.Not = nil
+	}
+
+	schema := &spec.Schema{SchemaProps: spec.SchemaProps{AllOf: append([]spec.Schema{outer}, branches...)}}
+	resolved, err := s.gens.resolve(schema, s.base)
+	if err != nil {
+		return nil, err
+	}
+	derived := *s
+	derived.schema = resolved.schema
+	derived.extraPatterns = append(s.extraPatterns[:len(s.extraPatterns):len(s.extraPatterns)], resolved.patterns...)
+	return &derived, nil
+}
+func (s *schemaOpts) exclusive(keyword string, indexes []int, value interface{}) (bool, error) {
+	var others []spec.Schema
+	switch keyword {
+	case "oneOf":
+		for i, branch := range s.schema.OneOf {
+			if i != indexes[0] {
+				others = append(others, branch)
+			}
+		}
+	case "not":
+		others = append(others, *s.schema.Not)
+	}
+
+	for _, other := range others {
+		matched, err := s.gens.matches(other, s.base, value)
+		if err != nil || matched {
+			return false, err
+		}
+	}
+	return true, nil
+}
+func (s *schemaOpts) pointer() string {
+	return s.path
+}
+func (s *schemaOpts) patterns() []string {
+	return s.extraPatterns
+}
+func (s *schemaOpts) recursion() error {
+	if len(s.cycle) == 0 {
+		return nil
+	}
+	return fmt.Errorf("recursive reference %s for [%s] at %q can't be nested more than %d times", strings.Join(s.cycle, " -> "), s.fieldName, s.path, s.gens.maxDepth)
+}
+func (s *schemaOpts) Rand() *rand.Rand {
+	return s.gens.random()
+}
+func (s *schemaOpts) Generator(name string) (ValueGenerator, bool) {
+	return s.gens.byName(name)
+}
+func (s *schemaOpts) locate(path string, targets modeTargets) {
+	s.path, s.targets = path, targets
+	s.mode |= targets[path]
+}
+func (s *schemaOpts) targeted() bool {
+	return s.targets.within(s.path)
+}
+func (s *schemaOpts) element(index int) GeneratorOpts {
+	elem := *s
+	elem.locate(s.path+"/"+strconv.Itoa(index), s.targets)
+	return &elem
+}
+func (s *schemaOpts) elements() int {
+	return s.targets.elements(s.path)
+}
+
+// inferType returns the type of a schema without a type: the type its format, pattern or enum imply, or else an object
+func inferType(schema *spec.Schema) string {
+	switch {
+	case len(schema.Properties) > 0 || schema.AdditionalProperties != nil:
+		return "object"
+	case schema.Items != nil:
+		return "array"
+	case schema.Format == "int32" || schema.Format == "int64":
+		return "integer"
+	case schema.Format == "float" || schema.Format == "double":
+		return "number"
+	case schema.Format != "" || schema.Pattern != "":
+		return "string"
+	}
+
+	var tpe string
+	for _, member := range schema.Enum {
+		var memberType string
+		switch v := member.(type) {
+		case string:
+			memberType = "string"
+		case bool:
+			memberType = "boolean"
+		case []interface{}:
+			memberType = "array"
+		case int, int32, int64:
+			memberType = "integer"
+		case float32:
+			memberType = numberType(float64(v))
+		case float64:
+			memberType = numberType(v)
+		case nil:
+			continue
+		default:
+			return "object"
+		}
+		switch {
+		case tpe == "" || tpe == "integer" && memberType == "number":
+			tpe = memberType
+		case tpe == "number" && memberType == "integer":
+		case tpe != memberType:
+			return "object"
+		}
+	}
+	if tpe == "" {
+		return "object"
+	}
+	return tpe
+}
+
+// numberType returns integer for a whole number, number otherwise
+func numberType(num float64) string {
+	if num == math.Trunc(num) {
+		return "integer"
+	}
+	return "number"
+}
@@ -3,7 +3,13 @@ package stubs
 import (
 	"fmt"
 	"math/rand"
+	"net"
+	"reflect"
+	"regexp"
+	"regexp/syntax"
+	"sort"
 	"strings"
+	"sync"
 	"time"
 
 	randomdata "github.com/Pallinder/go-randomdata"
@@ -15,8 +21,27 @@ import (
 	regen "github.com/zach-klippenstein/goregen"
 )
 
+const (
+	// maxAttempts is the number of times a value is generated again when it doesn't satisfy its validations
+	maxAttempts = 100
+
+	// syntaxFlags are the flags to parse patterns with, perl syntax understands flags like (?i) used by validation patterns
+	syntaxFlags = syntax.Perl
+)
+
 var (
+	// generatorAliases maps the alternative names of value generators to their names, guarded by aliasesLock
 	generatorAliases map[string]string
+	aliasesLock      sync.RWMutex
+
+	// randomdataLock guards the package level random source of the randomdata package
+	randomdataLock sync.Mutex
+
+	// fakerEntryPattern matches a reference to another entry in a format of the faker dictionary (eg. #{street_name})
+	fakerEntryPattern = regexp.MustCompile(`#\{[^}]+\}`)
+
+	// fakerDigitsPattern matches the placeholders for digits in a format of the faker dictionary
+	fakerDigitsPattern = regexp.MustCompile(`#+`)
 )
 
 func init() {
@@ -44,11 +69,28 @@ func init() {
 	RegisterAltGenNames("uuid4", "uuidv4")
 	RegisterAltGenNames("uuid3", "uuidv3")
 	RegisterAltGenNames("uuid5", "uuidv5")
+	RegisterAltGenNames("uuid7", "uuidv7")
+	RegisterAltGenNames("uri", "url", "link", "website")
+	RegisterAltGenNames("byte", "base64")
+	RegisterAltGenNames("password", "passwd", "secret")
+	RegisterAltGenNames("bsonobjectid", "bson-object-id", "object-id")
+	RegisterAltGenNames("currency", "currency-code")
+	RegisterAltGenNames("country-code", "country-iso")
+	RegisterAltGenNames("date-time", "datetime")
+	RegisterAltGenNames("duration", "duration-human", "durationhuman")
+	RegisterAltGenNames("duration-iso8601", "durationiso8601", "iso-duration")
+	RegisterAltGenNames("birthday", "birth-date", "date-of-birth", "dob")
+	RegisterAltGenNames("created-at", "created", "updated-at", "updated", "modified-at", "modified", "deleted-at")
+	RegisterAltGenNames("expires", "expires-at", "expiry", "expiration", "expiration-date", "valid-until")
 	RegisterAltGenNames("bool", "boolean")
+	RegisterAltGenNames("double", "number")
+	RegisterAltGenNames("int64", "integer")
 }
 
-// RegisterAltGenNames registers alternatives for a generator name
+// RegisterAltGenNames registers alternatives for a generator name, it is safe to call while stubs are generated
 func RegisterAltGenNames(key string, alts ...string) {
+	aliasesLock.Lock()
+	defer aliasesLock.Unlock()
 	if generatorAliases == nil {
 		generatorAliases = make(map[string]string, 300)
 	}
@@ -60,7 +102,10 @@ func RegisterAltGenNames(key string, alts ...string) {
 // ValueGenerator represents a function to generate a piece of random data
 type ValueGenerator func(GeneratorOpts) (interface{}, error)
 
-func newGenerator(lang string) (*generators, error) {
+// newGenerator creates the value generators for a language, all the random data is generated from the source.
+// When the source is nil the random data is seeded from the current time.
+// Value generators in the registries take precedence over the built-in value generators, in the order of the registries.
+func newGenerator(lang string, src rand.Source, registries ...*Registry) (*generators, error) {
 	if lang == "" {
 		lang = "en"
 	}
@@ -68,25 +113,59 @@ func newGenerator(lang string) (*generators, error) {
 	if err != nil {
 		return nil, err
 	}
+	faker.Dict = withFallback(faker.Dict)
+	if src == nil {
+		src = rand.NewSource(time.Now().UnixNano())
+	}
+	rnd := rand.New(src)
+	faker.Rand = rnd
+
 	g := &generators{
-		faker: faker,
-		conv:  conv.Conv{},
+		faker:      faker,
+		conv:       conv.Conv{},
+		rand:       rnd,
+		registries: registries,
+		maxDepth:   defaultMaxDepth,
+		now:        time.Now,
 	}
 	g.makeGenerators()
 	return g, nil
 }
 
+// withFallback returns a copy of the dictionary of a language completed with the english entries it lacks,
+// faker panics when a value generator needs an entry the dictionary doesn't have.
+// The formats of cell phone numbers aren't completed, faker falls back to the phone number formats of the language.
+func withFallback(dict map[string][]string) map[string][]string {
+	result := make(map[string][]string, len(faker.Dict["en"]))
+	for key, values := range faker.Dict["en"] {
+		if key != "phone_number.cell_phone" {
+			result[key] = values
+		}
+	}
+	for key, values := range dict {
+		result[key] = values
+	}
+	return result
+}
+
 type generators struct {
-	faker *faker.Faker
-	conv  conv.Converter
-	gens  map[string]ValueGenerator
+	faker      *faker.Faker
+	conv       conv.Converter
+	rand       *rand.Rand
+	registries []*Registry
+	resolver   *resolver
+	maxDepth   int
+	branches   func(Branch)
+	now        func() time.Time
+	gens       map[string]ValueGenerator
+	types      map[string]string
 }
 
 func (g *generators) makeGenerators() {
 	g.gens = map[string]ValueGenerator{
 		"characters":        g.intString(g.faker.Characters),
-		"noun":              g.string(randomdata.Noun),
-		"adjective":         g.string(randomdata.Noun),
+		"noun":              g.randomdata(randomdata.Noun),
+		"adjective":         g.randomdata(randomdata.Noun),
 		"word":              g.string(func() string { return g.faker.Words(1, false)[0] }),
 		"words":             g.intBoolStrings(g.faker.Words),
 		"sentence":          g.intBoolString(g.faker.Sentence),
@@ -95,9 +174,9 @@ func (g *generators) makeGenerators() {
 		"paragraphs":        g.intBoolStrings(g.faker.Paragraphs),
 		"city":              g.string(g.faker.City),
 		"street-name":       g.string(g.faker.StreetName),
-		"street-address":    g.string(g.faker.StreetAddress),
-		"secondary-address": g.string(g.faker.SecondaryAddress),
-		"postcode":          g.string(g.faker.PostCode),
+		"street-address":    g.stringError(g.numerify("address.street_address")),
+		"secondary-address": g.stringError(g.numerify("address.secondary_address")),
+		"postcode":          g.stringError(g.numerify("address.postcode")),
 		"street-suffix":     g.string(g.faker.StreetSuffix),
 		"city-suffix":       g.string(g.faker.CitySuffix),
 		"city-prefix":       g.string(g.faker.CityPrefix),
@@ -110,8 +189,8 @@ func (g *generators) makeGenerators() {
 		"company-suffix":    g.string(g.faker.CompanySuffix),
 		"company-slogan":    g.string(g.faker.CompanyCatchPhrase),
 		"company-bs":        g.string(g.faker.CompanyBs),
-		"landline":          g.string(g.faker.PhoneNumber),
-		"mobile":            g.string(g.faker.CellPhoneNumber),
+		"landline":          g.stringError(g.numerify("phone_number.formats")),
+		"mobile":            g.stringError(g.numerify("phone_number.cell_phone", "phone_number.formats")),
 		"email":             g.string(g.faker.Email),
 		"free-email":        g.string(g.faker.FreeEmail),
 		"safe-email":        g.string(g.faker.SafeEmail),
@@ -119,21 +198,21 @@ func (g *generators) makeGenerators() {
 		"hostname":          g.string(g.faker.DomainWord),
 		"domain":            g.string(g.faker.DomainName),
 		"domain-suffix":     g.string(g.faker.DomainSuffix),
-		"ipv4":              g.string(randomdata.IpV4Address),
-		"ipv6":              g.string(randomdata.IpV6Address),
-		"ip":                g.altws(randomdata.IpV4Address, randomdata.IpV6Address),
+		"ipv4":              g.string(g.ipv4),
+		"ipv6":              g.string(g.ipv6),
+		"ip":                g.altws(g.ipv4, g.ipv6),
 		"name":              g.string(g.faker.Name),
-		"silly-name":        g.string(randomdata.SillyName),
+		"silly-name":        g.randomdata(randomdata.SillyName),
 		"first-name":        g.string(g.faker.FirstName),
 		"last-name":         g.string(g.faker.LastName),
 		"name-prefix":       g.string(g.faker.NamePrefix),
 		"name-suffix":       g.string(g.faker.NameSuffix),
 		"job-title":         g.string(g.faker.JobTitle),
-		"credit-card":       g.fromPattern(govalidator.CreditCard),
-		"isbn":              g.altwsp(govalidator.ISBN10, govalidator.ISBN13),
-		"isbn10":            g.fromPattern(govalidator.ISBN10),
-		"isbn13":            g.fromPattern(govalidator.ISBN13),
-		"ssn":               g.fromPattern(govalidator.SSN),
+		"credit-card":       g.string(g.creditCard),
+		"isbn":              g.altws(g.isbn10, g.isbn13),
+		"isbn10":            g.string(g.isbn10),
+		"isbn13":            g.string(g.isbn13),
+		"ssn":               g.fromPattern(ssnPattern),
 		"hexcolor":          g.fromPattern(govalidator.Hexcolor),
 		"rgbcolor":          g.fromPattern(govalidator.RGBcolor),
 		"mac-address":       g.fromPattern("^([0-9A-Fa-f]{2}[:]){5}([0-9A-Fa-f]{2})$"),
@@ -141,62 +220,298 @@ func (g *generators) makeGenerators() {
 		"uuid3":             g.fromPattern(strfmt.UUID3Pattern),
 		"uuid4":             g.fromPattern(strfmt.UUID4Pattern),
 		"uuid5":             g.fromPattern(strfmt.UUID5Pattern),
+		"uuid7":             g.fromPattern(uuid7Pattern),
+		"uri":               g.string(g.uri),
+		"cidr":              g.string(g.cidr),
+		"byte":              g.string(g.base64),
+		"password":          g.fromPattern(passwordPattern),
+		"bsonobjectid":      g.fromPattern(bsonObjectIDPattern),
+		"ulid":              g.fromPattern(ulidPattern),
+		"currency":          g.string(g.pick(currencyCodes)),
+		"country-code":      g.string(g.pick(countryCodes)),
 		"bool":              g.bool,
+		"int32":             g.int32,
+		"int64":             g.int64,
+		"float":             g.float32,
+		"double":            g.float64,
+		"string":            g.string(func() string { return g.faker.Words(1, false)[0] }),
+		"array":             g.array,
+		"object":            g.object,
+		"null":              g.null,
+		"date":              g.timestamp("date"),
+		"date-time":         g.timestamp("date-time"),
+		"duration":          g.duration,
+		"duration-iso8601":  g.isoDuration,
+		"birthday":          g.timeHint("birthday"),
+		"created-at":        g.timeHint("created-at"),
+		"expires":           g.timeHint("expires"),
 	}
 
-	/* TODO:
-	* add date
-	* add date-time
-	* add duration
-	* add integers
-	* add decimals
-	* add slices
-	 */
+	// the swagger type of the values a generator produces, generators not listed here produce strings
+	g.types = map[string]string{
+		"words":      "array",
+		"sentences":  "array",
+		"paragraphs": "array",
+		"latitude":   "number",
+		"longitude":  "number",
+		"bool":       "boolean",
+		"int32":      "integer",
+		"int64":      "integer",
+		"float":      "number",
+		"double":     "number",
+		"array":      "array",
+		"object":     "object",
+		"null":       "null",
+	}
 }
 
 func normalizeGeneratorName(str string) string {
 	kn := strings.ToLower(str)
+	aliasesLock.RLock()
+	defer aliasesLock.RUnlock()
 	if k, ok := generatorAliases[kn]; ok {
 		return k
 	}
 	return kn
 }
 
+// For finds the value generator for the provided options.
+//
+// An explicitly named generator always wins. Otherwise the generator is resolved through a fallback chain:
+// a generator for the format (eg. email, uuid), a generator for the pattern of a string,
+// a generator inferred from the field name (eg. city, first-name)
+// and finally a generator for the type and numeric format (eg. integer, int32, double, boolean).
+// Inferred generators are only used when they produce values of the type the options ask for.
+// The generator honors the stub mode of the options it generates a value for.
+// A schema with oneOf, anyOf or not gets a generator which generates a value for the picked subschemas,
+// Valid values for a descriptor with an enum are picked from the enum.
 func (g *generators) For(opts GeneratorOpts) (ValueGenerator, bool) {
-	if gen, ok := g.gens[normalizeGeneratorName(opts.Name())]; ok {
+	if composed, ok := opts.(composedOpts); ok {
+		if keyword, _ := composed.composition(); keyword != "" {
+			// the options derived for the picked subschemas honor the stub mode
+			return g.composed, true
+		}
+	}
+	gen, found := g.lookup(opts)
+	if _, ok := opts.Enum(); ok && !found {
+		gen, found = g.enum, true
+	}
+	if !found {
+		return nil, false
+	}
+	return g.honorMode(gen), true
+}
+
+func (g *generators) lookup(opts GeneratorOpts) (ValueGenerator, bool) {
+	if gen, _, ok := g.named(normalizeGeneratorName(opts.Name())); ok {
 		return gen, true
 	}
-	if gen, ok := g.gens[normalizeGeneratorName(swag.ToCommandName(opts.FieldName()))]; ok {
+
+	tpe, format := opts.Type(), opts.Format()
+	if !isNumericFormat(format) {
+		if gen, ok := g.forType(formatGenerator(format), tpe); ok {
+			return gen, true
+		}
+	}
+	if _, ok := opts.Pattern(); ok && (tpe == "string" || tpe == "") {
+		// a pattern takes precedence over a generator inferred from the field name
+		return g.pattern, true
+	}
+	if gen, ok := g.forType(swag.ToCommandName(opts.FieldName()), tpe); ok {
 		return gen, true
 	}
-	return nil, false
+	if isNumericFormat(format) {
+		if gen, ok := g.forType(format, tpe); ok {
+			return gen, true
+		}
+	}
+	if tpe == "" {
+		return nil, false
+	}
+	return g.forType(tpe, tpe)
+}
+
+// forType returns the generator for the name when it produces values of the provided type
+func (g *generators) forType(name, tpe string) (ValueGenerator, bool) {
+	if name == "" {
+		return nil, false
+	}
+	gen, genType, ok := g.named(normalizeGeneratorName(name))
+	if !ok || (tpe != "" && genType != tpe) {
+		return nil, false
+	}
+	return gen, true
+}
+
+// named returns the generator for a normalized name and the type of the values it produces,
+// generators in the registries take precedence over the built-in generators
+func (g *generators) named(key string) (ValueGenerator, string, bool) {
+	for _, r := range g.registries {
+		if r == nil {
+			continue
+		}
+		if gen, tpe, ok := r.lookup(key); ok {
+			return gen, tpe, true
+		}
+	}
+	gen, ok := g.gens[key]
+	return gen, g.typeOf(key), ok
 }
 
-func seedAndReturnRandom(n int) int {
-	rand.Seed(time.Now().UnixNano())
-	return rand.Intn(n)
+func (g *generators) typeOf(name string) string {
+	if tpe, ok := g.types[name]; ok {
+		return tpe
+	}
+	return "string"
+}
+
+// byName returns the generator for a name, which honors the stub mode of the options it generates a value for
+func (g *generators) byName(name string) (ValueGenerator, bool) {
+	if g == nil {
+		return nil, false
+	}
+	gen, _, ok := g.named(normalizeGeneratorName(name))
+	if !ok {
+		return nil, false
+	}
+	return g.honorMode(gen), true
+}
+
+// names returns the names of all the available generators, in alphabetical order
+func (g *generators) names() []string {
+	seen := make(map[string]bool, len(g.gens))
+	for name := range g.gens {
+		seen[name] = true
+	}
+	for _, r := range g.registries {
+		if r == nil {
+			continue
+		}
+		for _, name := range r.Names() {
+			seen[name] = true
+		}
+	}
+
+	names := make([]string, 0, len(seen))
+	for name := range seen {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// random returns the random source of the generators, options which aren't bound to generators get a source seeded from the current time
+func (g *generators) random() *rand.Rand {
+	if g == nil {
+		return rand.New(rand.NewSource(time.Now().UnixNano()))
+	}
+	return g.rand
+}
+
+func isNumericFormat(format string) bool {
+	switch format {
+	case "int32", "int64", "float", "double":
+		return true
+	default:
+		return false
+	}
+}
+
+// randomInt64 returns a random number between lo and hi inclusive
+func (g *generators) randomInt64(lo, hi int64) int64 {
+	span := hi - lo + 1
+	if span <= 0 {
+		// the range overflows an int64
+		return lo + g.rand.Int63()
+	}
+	return lo + g.rand.Int63n(span)
+}
+
+// source returns a new random source seeded from the random source of the generators,
+// for libraries that take a source of their own
+func (g *generators) source() rand.Source {
+	return rand.NewSource(g.rand.Int63())
 }
 
 func (g *generators) altws(fns ...func() string) ValueGenerator {
 	return func(opts GeneratorOpts) (interface{}, error) {
-		idx := seedAndReturnRandom(len(fns))
+		idx := g.rand.Intn(len(fns))
 		return fns[idx](), nil
 	}
 }
 
-func (g *generators) altwsp(patterns ...string) ValueGenerator {
+func (g *generators) fromPattern(pattern string) ValueGenerator {
 	return func(opts GeneratorOpts) (interface{}, error) {
-		idx := seedAndReturnRandom(len(patterns))
-		return regen.Generate(patterns[idx])
+		return g.generateFromPattern(pattern)
 	}
 }
 
-func (g *generators) fromPattern(pattern string) ValueGenerator {
+// generateFromPattern generates a string for a pattern
+func (g *generators) generateFromPattern(pattern string) (string, error) {
+	gen, err := regen.NewGenerator(pattern, &regen.GeneratorArgs{Flags: syntaxFlags, RngSource: g.source()})
+	if err != nil {
+		return "", err
+	}
+	return gen.Generate(), nil
+}
+
+// randomdata wraps a function of the randomdata package, which uses a package level random source.
+// The package level source is replaced with a source seeded from the generators for every call.
+func (g *generators) randomdata(fn func() string) ValueGenerator {
 	return func(opts GeneratorOpts) (interface{}, error) {
-		return regen.Generate(pattern)
+		randomdataLock.Lock()
+		defer randomdataLock.Unlock()
+		randomdata.CustomRand(rand.New(g.source()))
+		return fn(), nil
 	}
 }
 
+// numerify generates a value from the first entry of the faker dictionary which exists, with random digits for its placeholders.
+// Faker fills the placeholders from the package level random source, so the entry is expanded with the source of the generators.
+func (g *generators) numerify(keys ...string) func() (string, error) {
+	return func() (string, error) {
+		for _, key := range keys {
+			formats, ok := g.faker.Dict[key]
+			if !ok {
+				continue
+			}
+			value := formats[g.rand.Intn(len(formats))]
+			for loc := fakerEntryPattern.FindStringIndex(value); loc != nil; loc = fakerEntryPattern.FindStringIndex(value) {
+				// an entry without a section refers to the section of the key (eg. #{street_name} in address.street_address)
+				entryKey := strings.ToLower(value[loc[0]+2 : loc[1]-1])
+				if !strings.Contains(entryKey, ".") {
+					entryKey = key[:strings.Index(key, ".")] + "." + entryKey
+				}
+				entry, ok := g.faker.Dict[entryKey]
+				if !ok {
+					return "", fmt.Errorf("no entry %s in the %s dictionary", entryKey, g.faker.Language)
+				}
+				value = value[:loc[0]] + entry[g.rand.Intn(len(entry))] + value[loc[1]:]
+			}
+			return fakerDigitsPattern.ReplaceAllStringFunc(value, func(placeholders string) string {
+				digits := make([]byte, len(placeholders))
+				for i := range digits {
+					digits[i] = byte('0' + g.rand.Intn(10))
+				}
+				return string(digits)
+			}), nil
+		}
+		return "", fmt.Errorf("no entry %s in the %s dictionary", keys[len(keys)-1], g.faker.Language)
+	}
+}
+
+func (g *generators) ipv4() string {
+	return net.IPv4(byte(g.rand.Intn(256)), byte(g.rand.Intn(256)), byte(g.rand.Intn(256)), byte(g.rand.Intn(256))).String()
+}
+
+func (g *generators) ipv6() string {
+	ip := make(net.IP, net.IPv6len)
+	for i := range ip {
+		ip[i] = byte(g.rand.Intn(256))
+	}
+	return ip.String()
+}
+
 func (g *generators) stringError(fn func() (string, error)) ValueGenerator {
 	return func(opts GeneratorOpts) (interface{}, error) {
 		return fn()
@@ -209,12 +524,6 @@ func (g *generators) string(fn func() string) ValueGenerator {
 	}
 }
 
-func (g *generators) stringer(fn func() fmt.Stringer) ValueGenerator {
-	return func(opts GeneratorOpts) (interface{}, error) {
-		return fn().String(), nil
-	}
-}
-
 func (g *generators) float(fn func() float64) ValueGenerator {
 	return func(opts GeneratorOpts) (interface{}, error) {
 		return fn(), nil
@@ -261,10 +570,29 @@ func (g *generators) intBoolString(fn func(int, bool) string) ValueGenerator {
 	}
 }
 
+// intBoolStrings generates a collection of texts, the number of texts is the first arg or else within the bounds of the collection.
+// A collection which doesn't have string items, or whose items the texts don't satisfy, is generated from its items.
 func (g *generators) intBoolStrings(fn func(int, bool) []string) ValueGenerator {
 	return func(opts GeneratorOpts) (interface{}, error) {
+		items, err := opts.Items()
+		if err != nil {
+			return nil, err
+		}
+		if items != nil && items.Type() != "string" {
+			return g.array(opts)
+		}
+
 		args := opts.Args()
 		count := 10
+		_, hasMin := opts.MinItems()
+		_, hasMax := opts.MaxItems()
+		if hasMin || hasMax {
+			minItems, maxItems, err := collectionBounds(opts)
+			if err != nil {
+				return nil, err
+			}
+			count = int(minItems) + g.rand.Intn(int(maxItems-minItems)+1)
+		}
 		if len(args) > 0 {
 			i, err := g.conv.Int(args[0])
 			if err != nil {
@@ -281,11 +609,270 @@ func (g *generators) intBoolStrings(fn func(int, bool) []string) ValueGenerator
 			supplemental = b
 		}
 
-		return fn(count, supplemental), nil
+		texts := fn(count, supplemental)
+		result := make([]interface{}, 0, len(texts))
+		for _, text := range texts {
+			if items != nil && violations(items, text) != Valid {
+				return g.array(opts)
+			}
+			result = append(result, text)
+		}
+		return result, nil
 	}
 }
 
 func (g *generators) bool(opts GeneratorOpts) (interface{}, error) {
-	answer := seedAndReturnRandom(2) == 2
+	answer := g.rand.Intn(2) == 1
 	return answer, nil
 }
+
+// enum picks a member of the enum of the options, numeric args are the weights of the members
+func (g *generators) enum(opts GeneratorOpts) (interface{}, error) {
+	enm, _ := opts.Enum()
+	return g.enumMember(opts, enm)
+}
+
+// pattern generates a string for the pattern of the options, within the length bounds of the options
+func (g *generators) pattern(opts GeneratorOpts) (interface{}, error) {
+	return g.text(opts, nil)
+}
+
+// int32 generates an integer within the minimum and maximum of the options and the range of an int32
+func (g *generators) int32(opts GeneratorOpts) (interface{}, error) {
+	return g.number(opts, "int32")
+}
+
+// int64 generates an integer within the minimum and maximum of the options and the range of an int64
+func (g *generators) int64(opts GeneratorOpts) (interface{}, error) {
+	return g.number(opts, "int64")
+}
+
+// float32 generates a number within the minimum and maximum of the options and the range of a float
+func (g *generators) float32(opts GeneratorOpts) (interface{}, error) {
+	return g.number(opts, "float")
+}
+
+// float64 generates a number within the minimum and maximum of the options and the range of a double
+func (g *generators) float64(opts GeneratorOpts) (interface{}, error) {
+	return g.number(opts, "double")
+}
+
+// array is a composite generator which generates a collection of values for the items of the collection.
+// The length of the collection is picked within the min items and max items bounds.
+// Validations of the items selected by the stub mode are violated by distinct members of the collection.
+func (g *generators) array(opts GeneratorOpts) (interface{}, error) {
+	mode := opts.Mode()
+	items, err := opts.Items()
+	if err != nil {
+		return nil, err
+	}
+	if items == nil {
+		// without items only an empty collection can be generated
+		if minItems, ok := opts.MinItems(); ok && minItems > 0 || mode&^InvalidMinItems != Valid {
+			return nil, fmt.Errorf("no items defined for collection [%s]", opts.FieldName())
+		}
+		return []interface{}{}, nil
+	}
+	if err := recursion(items); err != nil {
+		// members nested too deep stop the recursion by leaving the collection empty
+		if minItems, ok := opts.MinItems(); ok && minItems > 0 || mode&^InvalidMinItems != Valid {
+			return nil, err
+		}
+		return []interface{}{}, nil
+	}
+
+	minItems, maxItems, err := collectionBounds(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	// the members which violate a validation on behalf of the collection,
+	// the last member duplicates another member when unique items is violated
+	delegated := splitModes(mode &^ (collectionModes | InvalidEnum | Invalid))
+	reserved := int64(len(delegated))
+	if mode.Has(InvalidUniqueItems) {
+		reserved++
+		if reserved < 2 {
+			reserved = 2
+		}
+	}
+	if minItems < reserved {
+		minItems = reserved
+	}
+	if located, ok := items.(locatedOpts); ok && int64(located.elements()) > minItems {
+		// the collection contains all the members a stub mode is targeted at
+		minItems = int64(located.elements())
+	}
+	if minItems > maxItems {
+		return nil, fmt.Errorf("collection [%s] can't have %d items to be invalid for %s", opts.FieldName(), minItems, mode)
+	}
+
+	size := int(minItems) + g.rand.Intn(int(maxItems-minItems)+1)
+
+	targets := make(map[int]StubMode, len(delegated))
+	for i, idx := range g.rand.Perm(size - int(reserved) + len(delegated))[:len(delegated)] {
+		targets[idx] = delegated[i]
+	}
+
+	datagen, found := g.For(items)
+	if !found {
+		return nil, fmt.Errorf("no generator found for items of collection [%s]", opts.FieldName())
+	}
+
+	result := make([]interface{}, 0, size)
+	for len(result) < size {
+		idx := len(result)
+		if mode.Has(InvalidUniqueItems) && idx == size-1 {
+			result = append(result, result[g.rand.Intn(idx)])
+			continue
+		}
+
+		member := items
+		if located, ok := items.(locatedOpts); ok {
+			member = located.element(idx)
+		}
+		if m, ok := targets[idx]; ok {
+			member = withMode(member, member.Mode()|m)
+		}
+		value, err := g.collectionItem(opts, member, datagen, result)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, value)
+	}
+	return result, nil
+}
+
+// collectionItem generates a value for the items of a collection,
+// when the collection requires unique items a value already in the collection is generated again.
+func (g *generators) collectionItem(opts, items GeneratorOpts, datagen ValueGenerator, existing []interface{}) (interface{}, error) {
+	for attempt := 0; attempt < maxAttempts; attempt++ {
+		value, err := datagen(items)
+		if err != nil {
+			return nil, err
+		}
+		if !opts.UniqueItems() || !containsValue(existing, value) {
+			return value, nil
+		}
+	}
+	return nil, fmt.Errorf("unable to generate %d unique items for collection [%s] after %d attempts", len(existing)+1, opts.FieldName(), maxAttempts)
+}
+
+// collectionBounds returns the min and max length for a collection,
+// a collection without bounds gets between 1 and 5 items.
+func collectionBounds(opts GeneratorOpts) (int64, int64, error) {
+	mode := opts.Mode()
+	minItems, hasMin := opts.MinItems()
+	maxItems, hasMax := opts.MaxItems()
+	switch {
+	case mode.Has(InvalidMaxItems) && mode.Has(InvalidMinItems):
+		minItems, maxItems = maxItems+1, minItems-1
+	case mode.Has(InvalidMaxItems):
+		minItems = maxItems + 1
+		maxItems = minItems + 2
+	case mode.Has(InvalidMinItems):
+		minItems, maxItems = 0, minItems-1
+	default:
+		if !hasMin {
+			minItems = 1
+			if hasMax && maxItems < minItems {
+				minItems = maxItems
+			}
+		}
+		if !hasMax {
+			maxItems = minItems + 4
+		}
+	}
+	if minItems < 0 || maxItems < minItems {
+		return 0, 0, fmt.Errorf("no valid length for collection [%s] with min items %d and max items %d", opts.FieldName(), minItems, maxItems)
+	}
+	return minItems, maxItems, nil
+}
+
+func containsValue(values []interface{}, value interface{}) bool {
+	for _, v := range values {
+		if reflect.DeepEqual(v, value) {
+			return true
+		}
+	}
+	return false
+}
+
+// object is a composite generator which generates a value for each property of an object.
+// Required properties are always generated, optional properties are generated at random.
+// Validations selected by the stub mode are violated by the properties, a violation of required omits a required property.
+// An object with a discriminator is generated for one of the types which extend it, the discriminator names that type.
+func (g *generators) object(opts GeneratorOpts) (interface{}, error) {
+	var discriminator, typeName string
+	if poly, ok := opts.(polymorphicOpts); ok {
+		sub, err := poly.subtype()
+		if err != nil {
+			return nil, err
+		}
+		if sub != nil {
+			return g.object(sub)
+		}
+		discriminator, typeName = poly.discriminator()
+	}
+
+	props, err := properties(opts)
+	if err != nil {
+		return nil, err
+	}
+	mode := opts.Mode() &^ Invalid
+	if _, ok := opts.Enum(); ok {
+		// the object violates its own enum, otherwise a property violates the enum on behalf of the object
+		mode &^= InvalidEnum
+	}
+	targets, omit, err := g.distributeModes(opts, props, mode)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make(map[string]interface{}, len(props))
+	for _, prop := range props {
+		name := prop.FieldName()
+		mode, targeted := targets[name]
+		if located, ok := prop.(locatedOpts); ok && located.targeted() {
+			// the property contains a location a stub mode is targeted at
+			targeted = true
+		}
+		if name == omit || !targeted && !prop.Required() && g.rand.Intn(2) == 0 {
+			continue
+		}
+		if err := recursion(prop); err != nil {
+			if !targeted && !prop.Required() {
+				// an optional property nested too deep stops the recursion by being omitted
+				continue
+			}
+			return nil, err
+		}
+		if mode != Valid {
+			prop = withMode(prop, prop.Mode()|mode)
+		}
+		if name == discriminator && prop.Mode() == Valid {
+			// the discriminator names the type of the object
+			result[name] = typeName
+			continue
+		}
+
+		datagen, found := g.For(prop)
+		if !found {
+			return nil, fmt.Errorf("no generator found for property [%s]", name)
+		}
+		value, err := datagen(prop)
+		if err != nil {
+			return nil, err
+		}
+		if value == nil && prop.Mode().Has(InvalidRequired) {
+			// a property violates required by being omitted
+			continue
+		}
+		result[name] = value
+	}
+	return result, nil
+}
+
+func (g *generators) null(opts GeneratorOpts) (interface{}, error) {
+	return nil, nil
+}
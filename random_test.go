@@ -1,13 +1,20 @@
 package stubs
 
 import (
+	"math"
+	"math/rand"
+	"reflect"
 	"testing"
+	"time"
 
+	"github.com/go-openapi/spec"
+	"github.com/go-openapi/strfmt"
+	"github.com/go-openapi/swag"
 	"github.com/stretchr/testify/assert"
 )
 
 func TestGenerators_Characters(t *testing.T) {
-	gen, err := newGenerator("")
+	gen, err := newGenerator("", nil)
 	if assert.NoError(t, err) {
 		opts := &simpleOpts{name: "characters"}
 		fn, found := gen.For(opts)
@@ -29,7 +36,7 @@ func TestGenerators_Characters(t *testing.T) {
 }
 
 func TestGeneratorsBool(t *testing.T) {
-	gen, err := newGenerator("")
+	gen, err := newGenerator("", nil)
 	if assert.NoError(t, err) {
 		boolfn, found := gen.For(&simpleOpts{name: "bool"})
 		if assert.True(t, found) {
@@ -51,3 +58,530 @@ func TestGeneratorsBool(t *testing.T) {
 		}
 	}
 }
+
+func TestGenerators_ForType(t *testing.T) {
+	gen, err := newGenerator("", nil)
+	if assert.NoError(t, err) {
+		cases := []struct {
+			Type, Format string
+			Expected     interface{}
+		}{
+			{"integer", "", int64(0)},
+			{"integer", "int32", int32(0)},
+			{"integer", "int64", int64(0)},
+			{"number", "", float64(0)},
+			{"number", "float", float32(0)},
+			{"number", "double", float64(0)},
+			{"string", "", ""},
+			{"boolean", "", true},
+			{"array", "", []interface{}{}},
+			{"object", "", map[string]interface{}{}},
+		}
+
+		for _, tc := range cases {
+			opts := &simpleOpts{SimpleSchema: spec.SimpleSchema{Type: tc.Type, Format: tc.Format}}
+			fn, found := gen.For(opts)
+			if assert.True(t, found, "expected a generator for %s/%s", tc.Type, tc.Format) {
+				res, err := fn(opts)
+				if assert.NoError(t, err) {
+					assert.IsType(t, tc.Expected, res, "for %s/%s", tc.Type, tc.Format)
+				}
+			}
+		}
+
+		opts := &simpleOpts{SimpleSchema: spec.SimpleSchema{Type: "null"}}
+		fn, found := gen.For(opts)
+		if assert.True(t, found) {
+			res, err := fn(opts)
+			if assert.NoError(t, err) {
+				assert.Nil(t, res)
+			}
+		}
+
+		_, found = gen.For(new(simpleOpts))
+		assert.False(t, found)
+	}
+}
+
+func TestGenerators_ForInference(t *testing.T) {
+	gen, err := newGenerator("", nil)
+	if assert.NoError(t, err) {
+		// a field name refines a matching type
+		opts := &simpleOpts{fieldName: "email", SimpleSchema: spec.SimpleSchema{Type: "string"}}
+		fn, found := gen.For(opts)
+		if assert.True(t, found) {
+			res, err := fn(opts)
+			if assert.NoError(t, err) {
+				assert.Contains(t, res, "@")
+			}
+		}
+
+		// a field name is ignored when the inferred generator produces another type
+		opts = &simpleOpts{fieldName: "city", SimpleSchema: spec.SimpleSchema{Type: "integer"}}
+		fn, found = gen.For(opts)
+		if assert.True(t, found) {
+			res, err := fn(opts)
+			if assert.NoError(t, err) {
+				assert.IsType(t, int64(0), res)
+			}
+		}
+
+		// a format takes precedence over a field name
+		opts = &simpleOpts{fieldName: "email", SimpleSchema: spec.SimpleSchema{Type: "string", Format: "uuid"}}
+		fn, found = gen.For(opts)
+		if assert.True(t, found) {
+			res, err := fn(opts)
+			if assert.NoError(t, err) {
+				assert.Regexp(t, strfmt.UUIDPattern, res)
+			}
+		}
+	}
+}
+
+func TestGenerators_Array(t *testing.T) {
+	gen, err := newGenerator("", nil)
+	if assert.NoError(t, err) {
+		items := spec.NewItems().Typed("integer", "int32")
+		opts := &simpleOpts{fieldName: "ids", SimpleSchema: spec.SimpleSchema{Type: "array", Items: items}}
+		opts.CommonValidations.MinItems = swag.Int64(2)
+		opts.CommonValidations.MaxItems = swag.Int64(4)
+		fn, found := gen.For(opts)
+		if assert.True(t, found) {
+			for i := 0; i < 20; i++ {
+				res, err := fn(opts)
+				if assert.NoError(t, err) && assert.IsType(t, []interface{}{}, res) {
+					lst := res.([]interface{})
+					assert.True(t, len(lst) >= 2 && len(lst) <= 4, "expected between 2 and 4 items, got %d", len(lst))
+					for _, v := range lst {
+						assert.IsType(t, int32(0), v)
+					}
+				}
+			}
+		}
+
+		bools := &simpleOpts{fieldName: "flags", SimpleSchema: spec.SimpleSchema{Type: "array", Items: spec.NewItems().Typed("boolean", "")}}
+		bools.CommonValidations.UniqueItems = true
+		bools.CommonValidations.MinItems = swag.Int64(2)
+		bools.CommonValidations.MaxItems = swag.Int64(2)
+		res, err := gen.array(bools)
+		if assert.NoError(t, err) {
+			assert.ElementsMatch(t, []interface{}{true, false}, res)
+		}
+
+		bools.CommonValidations.MinItems = swag.Int64(3)
+		bools.CommonValidations.MaxItems = swag.Int64(3)
+		_, err = gen.array(bools)
+		assert.Error(t, err)
+
+		nothing := &simpleOpts{fieldName: "nothing", SimpleSchema: spec.SimpleSchema{Type: "array"}}
+		nothing.CommonValidations.MinItems = swag.Int64(1)
+		_, err = gen.array(nothing)
+		assert.Error(t, err)
+	}
+}
+
+func TestGenerators_Texts(t *testing.T) {
+	gen, err := newGenerator("", nil)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	// texts inferred from the field name honor the bounds of the collection
+	opts := &simpleOpts{fieldName: "words", SimpleSchema: spec.SimpleSchema{Type: "array", Items: spec.NewItems().Typed("string", "")}}
+	opts.CommonValidations.MaxItems = swag.Int64(3)
+	fn, found := gen.For(opts)
+	if assert.True(t, found) {
+		for i := 0; i < 20; i++ {
+			res, err := fn(opts)
+			if assert.NoError(t, err) && assert.IsType(t, []interface{}{}, res) {
+				assert.True(t, len(res.([]interface{})) <= 3, "expected at most 3 items, got %d", len(res.([]interface{})))
+			}
+		}
+	}
+
+	fn, _ = gen.For(&simpleOpts{name: "sentences"})
+	res, err := fn(&simpleOpts{name: "sentences"})
+	if assert.NoError(t, err) {
+		assert.Len(t, res, 10)
+	}
+
+	// a collection which doesn't have string items is generated from its items
+	opts = &simpleOpts{fieldName: "sentences", SimpleSchema: spec.SimpleSchema{Type: "array", Items: spec.NewItems().Typed("integer", "int32")}}
+	fn, found = gen.For(opts)
+	if assert.True(t, found) {
+		res, err := fn(opts)
+		if assert.NoError(t, err) && assert.IsType(t, []interface{}{}, res) {
+			for _, v := range res.([]interface{}) {
+				assert.IsType(t, int32(0), v)
+			}
+		}
+	}
+}
+
+func TestGenerators_Numbers(t *testing.T) {
+	gen, err := newGenerator("", nil)
+	if !assert.NoError(t, err) {
+		return
+	}
+	numbers := func(tpe, format string) *simpleOpts {
+		return &simpleOpts{fieldName: "value", SimpleSchema: spec.SimpleSchema{Type: tpe, Format: format}}
+	}
+
+	opts := numbers("integer", "int32")
+	opts.CommonValidations.Minimum = swag.Float64(-5)
+	opts.CommonValidations.Maximum = swag.Float64(5)
+	opts.CommonValidations.ExclusiveMaximum = true
+	opts.CommonValidations.MultipleOf = swag.Float64(2)
+	fn, found := gen.For(opts)
+	if assert.True(t, found) {
+		for i := 0; i < 20; i++ {
+			res, err := fn(opts)
+			if assert.NoError(t, err) {
+				assert.Contains(t, []int32{-4, -2, 0, 2, 4}, res)
+			}
+		}
+	}
+
+	// fractional multiples are rounded to the precision of the multiple
+	opts = numbers("number", "double")
+	opts.CommonValidations.Minimum = swag.Float64(0)
+	opts.CommonValidations.Maximum = swag.Float64(1)
+	opts.CommonValidations.MultipleOf = swag.Float64(0.1)
+	fn, _ = gen.For(opts)
+	for i := 0; i < 20; i++ {
+		res, err := fn(opts)
+		if assert.NoError(t, err) {
+			value := res.(float64)
+			assert.Equal(t, swag.FormatFloat64(value), swag.FormatFloat64(roundTo(value, 0.1)))
+		}
+	}
+
+	// the range of the format bounds the values
+	opts = numbers("integer", "int32")
+	opts.CommonValidations.Minimum = swag.Float64(math.MaxInt32 - 2)
+	fn, _ = gen.For(opts)
+	for i := 0; i < 20; i++ {
+		res, err := fn(opts)
+		if assert.NoError(t, err) {
+			assert.True(t, res.(int32) >= math.MaxInt32-2)
+		}
+	}
+	opts.CommonValidations.Minimum = swag.Float64(math.MaxInt32 + 1)
+	_, err = fn(opts)
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "no int32 for [value]")
+	}
+
+	opts = numbers("number", "float")
+	opts.CommonValidations.Maximum = swag.Float64(-math.MaxFloat32)
+	opts.CommonValidations.ExclusiveMaximum = true
+	fn, _ = gen.For(opts)
+	_, err = fn(opts)
+	assert.Error(t, err)
+
+	// constraints which admit no value
+	opts = numbers("integer", "")
+	opts.CommonValidations.Minimum = swag.Float64(1)
+	opts.CommonValidations.Maximum = swag.Float64(3)
+	opts.CommonValidations.MultipleOf = swag.Float64(4)
+	fn, _ = gen.For(opts)
+	_, err = fn(opts)
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "with minimum 1, maximum 3, multipleOf 4")
+	}
+
+	opts = numbers("integer", "")
+	opts.CommonValidations.Minimum = swag.Float64(1.2)
+	opts.CommonValidations.Maximum = swag.Float64(1.8)
+	_, err = fn(opts)
+	assert.Error(t, err)
+
+	// the values are generated from the random source
+	first, _ := newGenerator("", rand.NewSource(1))
+	second, _ := newGenerator("", rand.NewSource(1))
+	for _, format := range []string{"int32", "int64", "float", "double"} {
+		a, _, _ := first.named(format)
+		b, _, _ := second.named(format)
+		va, _ := a(numbers("", ""))
+		vb, _ := b(numbers("", ""))
+		assert.Equal(t, va, vb)
+	}
+}
+
+func TestGenerators_Strings(t *testing.T) {
+	gen, err := newGenerator("", nil)
+	if !assert.NoError(t, err) {
+		return
+	}
+	texts := func(fieldName string) *simpleOpts {
+		return &simpleOpts{fieldName: fieldName, SimpleSchema: spec.SimpleSchema{Type: "string"}}
+	}
+
+	// a pattern takes precedence over the generator inferred from the field name
+	opts := texts("email")
+	opts.CommonValidations.Pattern = `^[A-Z]{3}-\d{4}$`
+	fn, found := gen.For(opts)
+	if assert.True(t, found) {
+		for i := 0; i < 20; i++ {
+			res, err := fn(opts)
+			if assert.NoError(t, err) {
+				assert.Regexp(t, opts.CommonValidations.Pattern, res)
+			}
+		}
+	}
+
+	// the repetitions of a pattern adapt to the length bounds
+	opts = texts("code")
+	opts.CommonValidations.Pattern = `^\d{3}-[a-z]+$`
+	opts.CommonValidations.MinLength = swag.Int64(6)
+	opts.CommonValidations.MaxLength = swag.Int64(7)
+	fn, _ = gen.For(opts)
+	for i := 0; i < 20; i++ {
+		res, err := fn(opts)
+		if assert.NoError(t, err) {
+			assert.Regexp(t, opts.CommonValidations.Pattern, res)
+			assert.True(t, len(res.(string)) >= 6 && len(res.(string)) <= 7, "unexpected length of %q", res)
+		}
+	}
+
+	// semantic values are padded or truncated to the length bounds
+	opts = texts("city")
+	opts.CommonValidations.MinLength = swag.Int64(30)
+	opts.CommonValidations.MaxLength = swag.Int64(32)
+	fn, _ = gen.For(opts)
+	for i := 0; i < 20; i++ {
+		res, err := fn(opts)
+		if assert.NoError(t, err) {
+			assert.True(t, len(res.(string)) >= 30 && len(res.(string)) <= 32, "unexpected length of %q", res)
+		}
+	}
+	opts.CommonValidations.MinLength = swag.Int64(2)
+	opts.CommonValidations.MaxLength = swag.Int64(3)
+	for i := 0; i < 20; i++ {
+		res, err := fn(opts)
+		if assert.NoError(t, err) {
+			assert.True(t, len(res.(string)) >= 2 && len(res.(string)) <= 3, "unexpected length of %q", res)
+		}
+	}
+
+	// a pattern which can't produce a string within the length bounds
+	opts = texts("code")
+	opts.CommonValidations.Pattern = `^\d{5}$`
+	opts.CommonValidations.MaxLength = swag.Int64(3)
+	fn, _ = gen.For(opts)
+	_, err = fn(opts)
+	assert.Error(t, err)
+}
+
+func TestGenerators_Enum(t *testing.T) {
+	gen, err := newGenerator("", nil)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	// the enum takes precedence over the generator inferred from the field name
+	enm := []interface{}{"available", "pending", "sold"}
+	opts := &schemaOpts{fieldName: "city", schema: spec.StringProperty().WithEnum(enm...)}
+	fn, found := gen.For(opts)
+	if assert.True(t, found) {
+		counts := make(map[interface{}]int)
+		for i := 0; i < 300; i++ {
+			res, err := fn(opts)
+			if assert.NoError(t, err) {
+				counts[res]++
+			}
+		}
+		assert.Len(t, counts, 3)
+		for _, v := range enm {
+			assert.True(t, counts[v] > 50, "%v was picked %d times out of 300", v, counts[v])
+		}
+
+		// the args are the weights of the members
+		opts.args = []interface{}{8, 2, 0}
+		counts = make(map[interface{}]int)
+		for i := 0; i < 300; i++ {
+			res, err := fn(opts)
+			if assert.NoError(t, err) {
+				counts[res]++
+			}
+		}
+		assert.Zero(t, counts["sold"])
+		assert.True(t, counts["available"] > counts["pending"])
+
+		opts.args = []interface{}{1, 2}
+		_, err = fn(opts)
+		assert.Error(t, err)
+	}
+
+	// members of a numeric enum have the go type of the format
+	opts = &schemaOpts{fieldName: "size", schema: spec.Int32Property().WithEnum(float64(1), float64(2))}
+	fn, _ = gen.For(opts)
+	res, err := fn(opts)
+	if assert.NoError(t, err) {
+		assert.Contains(t, []interface{}{int32(1), int32(2)}, res)
+	}
+
+	// a boolean can't be outside an enum of both booleans
+	opts = &schemaOpts{fieldName: "flag", schema: spec.BoolProperty().WithEnum(true, false), mode: InvalidEnum}
+	fn, _ = gen.For(opts)
+	_, err = fn(opts)
+	assert.Error(t, err)
+}
+
+func TestGenerators_Dates(t *testing.T) {
+	gen, err := newGenerator("", nil)
+	if !assert.NoError(t, err) {
+		return
+	}
+	now := time.Date(2020, 6, 15, 12, 0, 0, 0, time.UTC)
+	gen.now = func() time.Time { return now }
+
+	dates := func(fieldName, format string, args ...interface{}) *simpleOpts {
+		return &simpleOpts{fieldName: fieldName, args: args, SimpleSchema: spec.SimpleSchema{Type: "string", Format: format}}
+	}
+	generate := func(opts GeneratorOpts) interface{} {
+		fn, found := gen.For(opts)
+		if !assert.True(t, found) {
+			return nil
+		}
+		res, err := fn(opts)
+		assert.NoError(t, err)
+		return res
+	}
+	parse := func(value interface{}) time.Time {
+		dt, err := strfmt.ParseDateTime(value.(string))
+		assert.NoError(t, err)
+		return time.Time(dt)
+	}
+
+	for i := 0; i < 20; i++ {
+		res := generate(dates("day", "date"))
+		if assert.Regexp(t, `^\d{4}-\d{2}-\d{2}$`, res) {
+			assert.True(t, strfmt.IsDate(res.(string)))
+		}
+
+		value := parse(generate(dates("timestamp", "date-time")))
+		assert.True(t, value.After(now.Add(-year)) && value.Before(now.Add(year)))
+
+		// field names are hints for the range of time
+		value = parse(generate(dates("created_at", "date-time")))
+		assert.False(t, value.After(now))
+		value = parse(generate(dates("expires", "date-time")))
+		assert.False(t, value.Before(now))
+		birthday, err := time.Parse(strfmt.RFC3339FullDate, generate(dates("birthday", "")).(string))
+		if assert.NoError(t, err) {
+			assert.True(t, birthday.Before(now.Add(-18*year)))
+		}
+
+		// the args are the range of time
+		value = parse(generate(dates("updated", "date-time", "past 30 days")))
+		assert.True(t, !value.After(now) && !value.Before(now.Add(-30*day)))
+		value = parse(generate(dates("due", "date-time", "next 2 weeks")))
+		assert.True(t, !value.Before(now) && !value.After(now.Add(14*day)))
+		res = generate(dates("day", "date", "between 2019-01-01 and 2019-01-31"))
+		assert.Regexp(t, `^2019-01-\d{2}$`, res)
+		res = generate(dates("day", "date", "2019-02-01", "2019-02-28"))
+		assert.Regexp(t, `^2019-02-\d{2}$`, res)
+
+		res = generate(dates("timeout", "duration"))
+		if assert.IsType(t, "", res) {
+			d, err := strfmt.ParseDuration(res.(string))
+			assert.NoError(t, err)
+			assert.True(t, d >= time.Minute && d <= 30*day)
+		}
+		res = generate(dates("timeout", "duration", "between 1h and 2h"))
+		if assert.IsType(t, "", res) {
+			d, err := strfmt.ParseDuration(res.(string))
+			assert.NoError(t, err)
+			assert.True(t, d >= time.Hour && d <= 2*time.Hour)
+		}
+	}
+
+	// strfmt values instead of strings
+	assert.IsType(t, strfmt.Date{}, generate(dates("day", "date", "strfmt")))
+	assert.IsType(t, strfmt.DateTime{}, generate(dates("day", "date-time", "strfmt", "past 1 day")))
+	assert.IsType(t, strfmt.Duration(0), generate(dates("timeout", "duration", "strfmt")))
+
+	opts := dates("day", "date", "someday")
+	fn, _ := gen.For(opts)
+	_, err = fn(opts)
+	assert.Error(t, err)
+}
+
+// registeredFormats returns the names of the formats in the default strfmt registry,
+// the registry has no method to list them so they're read from its unexported data
+func registeredFormats(t *testing.T) []string {
+	data := reflect.ValueOf(strfmt.Default).Elem().FieldByName("data")
+	if !assert.True(t, data.IsValid(), "unable to list the formats of the strfmt registry") {
+		return nil
+	}
+	names := make([]string, 0, data.Len())
+	for i := 0; i < data.Len(); i++ {
+		names = append(names, data.Index(i).FieldByName("OrigName").String())
+	}
+	return names
+}
+
+func TestGenerators_Formats(t *testing.T) {
+	gen, err := newGenerator("", nil)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	formats := registeredFormats(t)
+	assert.Contains(t, formats, "bsonobjectid")
+	for _, format := range formats {
+		opts := &simpleOpts{fieldName: "value", SimpleSchema: spec.SimpleSchema{Type: "string", Format: format}}
+		fn, found := gen.lookup(opts)
+		if !assert.True(t, found, "no generator for format %q", format) {
+			continue
+		}
+		for i := 0; i < 20; i++ {
+			res, err := fn(opts)
+			if assert.NoError(t, err, format) && assert.IsType(t, "", res, format) {
+				assert.True(t, strfmt.Default.Validates(format, res.(string)), "%q is not a valid %s", res, format)
+			}
+		}
+	}
+
+	// a country field is a country name, a country format is a country code
+	fn, _ := gen.For(&simpleOpts{fieldName: "country", SimpleSchema: spec.SimpleSchema{Type: "string"}})
+	res, err := fn(&simpleOpts{fieldName: "country"})
+	assert.NoError(t, err)
+	assert.False(t, strfmt.IsCountry(res.(string)))
+}
+
+func TestGenerators_Languages(t *testing.T) {
+	for _, lang := range []string{"de", "nl", "zh-CN", "en-bork"} {
+		gen, err := newGenerator(lang, nil)
+		if !assert.NoError(t, err, lang) {
+			continue
+		}
+		// the entries a language lacks are taken from english
+		for name := range gen.gens {
+			fn, found := gen.byName(name)
+			if assert.True(t, found, name) {
+				_, err := fn(&simpleOpts{fieldName: name})
+				assert.NoError(t, err, "%s: %s", lang, name)
+			}
+		}
+	}
+
+	gen, err := newGenerator("de", nil)
+	if assert.NoError(t, err) {
+		delete(gen.faker.Dict, "name.name")
+		fn, _ := gen.byName("name")
+		_, err := fn(&simpleOpts{fieldName: "owner"})
+		if assert.Error(t, err) {
+			assert.Contains(t, err.Error(), "owner")
+			assert.Contains(t, err.Error(), "name.name")
+		}
+
+		delete(gen.faker.Dict, "address.street_name")
+		fn, _ = gen.byName("street-address")
+		_, err = fn(&simpleOpts{fieldName: "street"})
+		if assert.Error(t, err) {
+			assert.Contains(t, err.Error(), "address.street_name")
+		}
+	}
+}
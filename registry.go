@@ -0,0 +1,73 @@
+package stubs
+
+import (
+	"sort"
+	"sync"
+)
+
+// DefaultRegistry is the registry of value generators available to every generator
+var DefaultRegistry = NewRegistry()
+
+// Registry of value generators, so they are addressable by name.
+//
+// A registered value generator takes precedence over a built-in value generator with the same name,
+// and is inferred from field names and formats just like the built-in value generators.
+// Value generators get the random source and the other value generators from options which implement SourceOpts.
+// A registry is safe for concurrent use.
+type Registry struct {
+	lock sync.RWMutex
+	gens map[string]registeredGenerator
+}
+
+type registeredGenerator struct {
+	gen ValueGenerator
+	tpe string
+}
+
+// NewRegistry creates a new empty registry
+func NewRegistry() *Registry {
+	return &Registry{gens: make(map[string]registeredGenerator)}
+}
+
+// Register a value generator for a name, tpe is the swagger type of the values it produces (eg. string, integer).
+// Registering a value generator for a name which is already registered overrides the registered value generator.
+func (r *Registry) Register(name, tpe string, gen ValueGenerator) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.gens[normalizeGeneratorName(name)] = registeredGenerator{gen: gen, tpe: tpe}
+}
+
+// Unregister the value generator for a name, returns false when no value generator was registered for the name
+func (r *Registry) Unregister(name string) bool {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	key := normalizeGeneratorName(name)
+	_, ok := r.gens[key]
+	delete(r.gens, key)
+	return ok
+}
+
+// Lookup the value generator registered for a name
+func (r *Registry) Lookup(name string) (ValueGenerator, bool) {
+	gen, _, ok := r.lookup(normalizeGeneratorName(name))
+	return gen, ok
+}
+
+// Names of the registered value generators, in alphabetical order
+func (r *Registry) Names() []string {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	names := make([]string, 0, len(r.gens))
+	for name := range r.gens {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (r *Registry) lookup(key string) (ValueGenerator, string, bool) {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	reg, ok := r.gens[key]
+	return reg.gen, reg.tpe, ok
+}
@@ -0,0 +1,150 @@
+package stubs
+
+import (
+	"fmt"
+	"math/rand"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/go-openapi/spec"
+	"github.com/stretchr/testify/assert"
+)
+
+func constant(value interface{}) ValueGenerator {
+	return func(GeneratorOpts) (interface{}, error) {
+		return value, nil
+	}
+}
+
+func TestRegistry(t *testing.T) {
+	reg := NewRegistry()
+	reg.Register("Order-ID", "string", constant("order"))
+	reg.Register("sku", "string", constant("sku"))
+	assert.Equal(t, []string{"order-id", "sku"}, reg.Names())
+
+	gen, ok := reg.Lookup("order-id")
+	if assert.True(t, ok) {
+		res, err := gen(nil)
+		if assert.NoError(t, err) {
+			assert.Equal(t, "order", res)
+		}
+	}
+
+	reg.Register("sku", "string", constant("overridden"))
+	gen, ok = reg.Lookup("SKU")
+	if assert.True(t, ok) {
+		res, _ := gen(nil)
+		assert.Equal(t, "overridden", res)
+	}
+
+	assert.True(t, reg.Unregister("sku"))
+	assert.False(t, reg.Unregister("sku"))
+	_, ok = reg.Lookup("sku")
+	assert.False(t, ok)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			name := fmt.Sprintf("gen-%d", i)
+			reg.Register(name, "string", constant(name))
+			// aliases are registered while other registries normalize names
+			RegisterAltGenNames(name, "alt-"+name)
+			_, ok := reg.Lookup("alt-" + name)
+			assert.True(t, ok)
+			reg.Names()
+			reg.Unregister(name)
+		}(i)
+	}
+	wg.Wait()
+	assert.Equal(t, []string{"order-id"}, reg.Names())
+}
+
+func TestGenerator_Registry(t *testing.T) {
+	DefaultRegistry.Register("sku", "string", func(opts GeneratorOpts) (interface{}, error) {
+		sourced, ok := opts.(SourceOpts)
+		if !ok {
+			return nil, fmt.Errorf("no random source")
+		}
+		return fmt.Sprintf("SKU-%06d", sourced.Rand().Intn(1000000)), nil
+	})
+	defer DefaultRegistry.Unregister("sku")
+
+	reg := NewRegistry()
+	reg.Register("city", "string", constant("Gotham"))
+	reg.Register("tenant-slug", "string", func(opts GeneratorOpts) (interface{}, error) {
+		sourced, ok := opts.(SourceOpts)
+		if !ok {
+			return nil, fmt.Errorf("no value generators")
+		}
+		company, ok := sourced.Generator("company")
+		if !ok {
+			return nil, fmt.Errorf("no company generator")
+		}
+		name, err := company(opts)
+		if err != nil {
+			return nil, err
+		}
+		return strings.ToLower(strings.Join(strings.Fields(name.(string)), "-")), nil
+	})
+
+	schema := new(spec.Schema).
+		Typed("object", "").
+		WithRequired("sku", "city", "tenantSlug").
+		SetProperty("sku", *spec.StringProperty()).
+		SetProperty("city", *spec.StringProperty()).
+		SetProperty("tenantSlug", *spec.StringProperty())
+
+	gen := &Generator{Registry: reg, Source: rand.NewSource(1)}
+	res, err := gen.GenSchema("Order", schema)
+	if assert.NoError(t, err) {
+		order := res.(map[string]interface{})
+		assert.Regexp(t, `^SKU-\d{6}$`, order["sku"])
+		assert.Equal(t, "Gotham", order["city"])
+		assert.Regexp(t, `^[a-z0-9-]+$`, order["tenantSlug"])
+	}
+
+	gen.Source = rand.NewSource(1)
+	again, err := gen.GenSchema("Order", schema)
+	if assert.NoError(t, err) {
+		assert.Equal(t, res, again)
+	}
+
+	// built-in generators are used again when the registration is gone
+	reg.Unregister("city")
+	res, err = gen.GenSchema("Order", schema)
+	if assert.NoError(t, err) {
+		assert.NotEqual(t, "Gotham", res.(map[string]interface{})["city"])
+	}
+
+	names, err := gen.Names()
+	if assert.NoError(t, err) {
+		assert.Contains(t, names, "sku")
+		assert.Contains(t, names, "tenant-slug")
+		assert.Contains(t, names, "first-name")
+	}
+
+	// options which violate a validation still give the random source and the value generators
+	gen.Mode = InvalidMaxLength
+	schema.Properties["sku"] = *spec.StringProperty().WithMaxLength(20)
+	res, err = gen.GenSchema("Order", schema)
+	if assert.NoError(t, err) {
+		order := res.(map[string]interface{})
+		assert.Regexp(t, `^SKU-\d{6}`, order["sku"])
+		assert.Regexp(t, `^[a-z0-9-]+$`, order["tenantSlug"])
+	}
+}
+
+func TestSourceOpts(t *testing.T) {
+	// options implemented outside the package don't have to give the random source and the value generators
+	type plainOpts struct{ GeneratorOpts }
+	opts := withMode(plainOpts{&simpleOpts{fieldName: "code"}}, InvalidMaxLength)
+	sourced, ok := opts.(SourceOpts)
+	if assert.True(t, ok) {
+		assert.NotNil(t, sourced.Rand())
+		_, found := sourced.Generator("company")
+		assert.False(t, found)
+	}
+}
@@ -0,0 +1,471 @@
+package stubs
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/go-openapi/analysis"
+	"github.com/go-openapi/spec"
+)
+
+const (
+	jsonMime       = "application/json"
+	urlencodedMime = "application/x-www-form-urlencoded"
+	multipartMime  = "multipart/form-data"
+)
+
+// Request is a stub for a request of an operation, with the parameters serialized as they're sent over http
+type Request struct {
+	OperationID string `json:"operationId"`
+	Method      string `json:"method"`
+
+	// Path is the path of the operation prefixed with the base path of the document, with the path parameters substituted
+	Path   string      `json:"path"`
+	Query  url.Values  `json:"query,omitempty"`
+	Header http.Header `json:"header,omitempty"`
+
+	// Form has the form parameters, the content of file parameters is in Files
+	Form  url.Values        `json:"form,omitempty"`
+	Files map[string][]byte `json:"files,omitempty"`
+
+	// Body is the value of the body parameter, nil when the operation has no body parameter or the body is omitted
+	Body interface{} `json:"body"`
+
+	// ContentType is the media type for the body or the form, picked from the media types the operation consumes
+	ContentType string `json:"contentType,omitempty"`
+}
+
+// MarshalJSON encodes the request with the content of the files as text, content which isn't utf-8 is encoded in base64
+func (r Request) MarshalJSON() ([]byte, error) {
+	type request Request
+	var files map[string]interface{}
+	if len(r.Files) > 0 {
+		files = make(map[string]interface{}, len(r.Files))
+		for name, content := range r.Files {
+			if utf8.Valid(content) {
+				files[name] = string(content)
+			} else {
+				files[name] = content
+			}
+		}
+	}
+	return json.Marshal(struct {
+		request
+		Files map[string]interface{} `json:"files,omitempty"`
+	}{request(r), files})
+}
+
+// GenRequest generates a request stub for the operation with the id, in the document of the generator
+func (s *Generator) GenRequest(operationID string) (*Request, error) {
+	analyzed, err := s.analyzer()
+	if err != nil {
+		return nil, err
+	}
+	method, path, operation, ok := analyzed.OperationForName(operationID)
+	if !ok {
+		return nil, fmt.Errorf("no operation found with id %s", operationID)
+	}
+	return s.genRequest(analyzed, method, path, operation)
+}
+
+// GenRequestFor generates a request stub for the operation for the method and path (eg. GET /pets/{petId}),
+// in the document of the generator
+func (s *Generator) GenRequestFor(method, path string) (*Request, error) {
+	analyzed, err := s.analyzer()
+	if err != nil {
+		return nil, err
+	}
+	operation, ok := analyzed.OperationFor(method, path)
+	if !ok {
+		return nil, fmt.Errorf("no operation found for %s %s", strings.ToUpper(method), path)
+	}
+	return s.genRequest(analyzed, strings.ToUpper(method), path, operation)
+}
+
+// analyzer returns the analysis of the document of the generator,
+// the analysis is reused as long as the document doesn't change
+func (s *Generator) analyzer() (*analysis.Spec, error) {
+	if s.Spec == nil {
+		return nil, fmt.Errorf("a generator needs a document to generate stubs for operations")
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.analyzed == nil || s.analyzedSpec != s.Spec {
+		s.analyzed, s.analyzedSpec = analysis.New(s.Spec), s.Spec
+	}
+	return s.analyzed, nil
+}
+
+// requestParam is a parameter of a request, located at /{in}/{name} in the request
+type requestParam struct {
+	param *spec.Parameter
+	path  string
+}
+
+// genRequest generates the parameters of an operation. The stub mode of the generator applies to a random parameter
+// which can violate it, the modes for locations apply to the parameters at /{in}/{name} (eg. /query/limit or /body/name).
+func (s *Generator) genRequest(analyzed *analysis.Spec, method, path string, operation *spec.Operation) (*Request, error) {
+	generator, err := s.generators()
+	if err != nil {
+		return nil, err
+	}
+
+	byKey := analyzed.ParamsFor(method, path)
+	keys := make([]string, 0, len(byKey))
+	for key := range byKey {
+		keys = append(keys, key)
+	}
+	// the parameters are generated in a fixed order, so the same source produces the same request
+	sort.Strings(keys)
+	params := make([]requestParam, 0, len(keys))
+	for _, key := range keys {
+		param := byKey[key]
+		location := "/" + param.In + "/" + pointerEscaper.Replace(param.Name)
+		if param.In == "body" {
+			location = "/body"
+		}
+		params = append(params, requestParam{param: &param, path: location})
+	}
+
+	paths := make([]string, len(params))
+	for i, rp := range params {
+		paths[i] = rp.path
+	}
+	targets, err := s.assignMode(generator, "parameter of operation "+operation.ID, paths, func(i int, targets modeTargets) (GeneratorOpts, error) {
+		return generator.requestParamOpts(params[i], targets)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	req := &Request{
+		OperationID: operation.ID,
+		Method:      method,
+		Query:       make(url.Values),
+		Header:      make(http.Header),
+		Form:        make(url.Values),
+		Files:       make(map[string][]byte),
+		ContentType: contentType(analyzed.ConsumesFor(operation), params),
+	}
+	values := make(map[string]string)
+	for _, rp := range params {
+		param := rp.param
+		if !param.Required && !targets.within(rp.path) && generator.rand.Intn(2) == 0 {
+			continue
+		}
+		value, err := s.genRequestParam(generator, rp, targets)
+		if err != nil {
+			return nil, err
+		}
+		if value == nil {
+			// a required parameter violates required by being omitted
+			continue
+		}
+
+		switch param.In {
+		case "body":
+			req.Body = value
+		case "path":
+			values[param.Name] = strings.Join(serialize(value, param.CollectionFormat, param.Items), ",")
+		case "query":
+			for _, v := range serialize(value, param.CollectionFormat, param.Items) {
+				req.Query.Add(param.Name, v)
+			}
+		case "header":
+			req.Header.Set(param.Name, strings.Join(serialize(value, param.CollectionFormat, param.Items), ","))
+		case "formData":
+			if content, ok := value.([]byte); ok {
+				req.Files[param.Name] = content
+				continue
+			}
+			for _, v := range serialize(value, param.CollectionFormat, param.Items) {
+				req.Form.Add(param.Name, v)
+			}
+		}
+	}
+
+	req.Path = strings.TrimSuffix(s.Spec.BasePath, "/") + expandPath(path, values)
+	return req, nil
+}
+
+// assignMode returns the stub modes for the locations in a request or a response, the stub mode of the generator
+// is assigned to a random part at one of the paths which can violate it.
+// The options are the options of the part at the path with an index, for the stub modes of the targets.
+func (s *Generator) assignMode(generator *generators, description string, paths []string, options func(int, modeTargets) (GeneratorOpts, error)) (modeTargets, error) {
+	targets := make(modeTargets, len(s.Modes)+1)
+	for k, v := range s.Modes {
+		targets[k] = v
+	}
+	if s.Mode == Valid {
+		return targets, nil
+	}
+
+	var candidates []string
+	for i, path := range paths {
+		opts, err := options(i, make(modeTargets))
+		if err != nil {
+			return nil, err
+		}
+		if available := violable(opts); available != Valid && available&(s.Mode&^Invalid) == s.Mode&^Invalid {
+			candidates = append(candidates, path)
+		}
+	}
+	if len(candidates) == 0 {
+		return nil, fmt.Errorf("no %s can be %s", description, describeMode(s.Mode))
+	}
+	targets[candidates[generator.rand.Intn(len(candidates))]] |= s.Mode
+	return targets, nil
+}
+
+// requestParamOpts returns the options for a parameter of a request
+func (g *generators) requestParamOpts(rp requestParam, targets modeTargets) (GeneratorOpts, error) {
+	if rp.param.In == "body" && rp.param.Schema != nil {
+		return g.schemaOpts(rp.param.Name, rp.param.Schema, rp.path, targets)
+	}
+	return g.paramOpts(rp.param.Name, rp.param, rp.path, targets)
+}
+
+// genRequestParam generates a value for a parameter of a request, a file parameter gets some text as its content
+func (s *Generator) genRequestParam(generator *generators, rp requestParam, targets modeTargets) (interface{}, error) {
+	param := rp.param
+	if param.Type == "file" {
+		return []byte(generator.faker.Paragraph(3, false)), nil
+	}
+
+	opts, err := generator.requestParamOpts(rp, targets)
+	if err != nil {
+		return nil, err
+	}
+	if param.In == "body" && param.Schema != nil {
+		schemaOpts := opts.(*schemaOpts)
+		return s.generate(generator, opts, "parameter ["+param.Name+"]", targets.under(rp.path), generator.checkSchema(param.Name, param.Schema, schemaOpts.schema))
+	}
+	return s.generate(generator, opts, "parameter ["+param.Name+"]", targets.under(rp.path), checkParam(param))
+}
+
+// contentType picks the media type for the body or the form of a request from the media types the operation consumes,
+// a form with files is sent as multipart form data
+func contentType(consumes []string, params []requestParam) string {
+	var body, form, files bool
+	for _, rp := range params {
+		switch {
+		case rp.param.In == "body":
+			body = true
+		case rp.param.In == "formData":
+			form = true
+			files = files || rp.param.Type == "file"
+		}
+	}
+
+	switch {
+	case files:
+		return negotiate(nil, multipartMime)
+	case form:
+		return negotiate(consumes, urlencodedMime, multipartMime)
+	case body:
+		return negotiate(consumes, jsonMime)
+	default:
+		return ""
+	}
+}
+
+// negotiate picks the first of the preferred media types which is available, then a json media type (eg. application/problem+json).
+// Without a match it picks the first available media type, or the first preferred media type when none are available.
+func negotiate(available []string, preferred ...string) string {
+	for _, want := range preferred {
+		for _, mediaType := range available {
+			if mt, _, err := mime.ParseMediaType(mediaType); err == nil && mt == want {
+				return mediaType
+			}
+		}
+	}
+	for _, mediaType := range available {
+		if mt, _, err := mime.ParseMediaType(mediaType); err == nil && strings.HasSuffix(mt, "+json") {
+			return mediaType
+		}
+	}
+	if len(available) > 0 {
+		return available[0]
+	}
+	return preferred[0]
+}
+
+// serialize returns the values of a parameter as strings, the members of a collection are joined by the collection format.
+// A collection in the multi format has a value per member.
+func serialize(value interface{}, collectionFormat string, items *spec.Items) []string {
+	rv := reflect.ValueOf(value)
+	if rv.Kind() != reflect.Slice {
+		return []string{formatValue(value)}
+	}
+
+	var itemsFormat string
+	var nested *spec.Items
+	if items != nil {
+		itemsFormat, nested = items.CollectionFormat, items.Items
+	}
+	members := make([]string, 0, rv.Len())
+	for i := 0; i < rv.Len(); i++ {
+		members = append(members, strings.Join(serialize(rv.Index(i).Interface(), itemsFormat, nested), separator(itemsFormat)))
+	}
+	if collectionFormat == "multi" {
+		return members
+	}
+	return []string{strings.Join(members, separator(collectionFormat))}
+}
+
+// separator returns the separator of the members of a collection in a collection format, csv is the default format
+func separator(collectionFormat string) string {
+	switch collectionFormat {
+	case "ssv":
+		return " "
+	case "tsv":
+		return "\t"
+	case "pipes":
+		return "|"
+	default:
+		return ","
+	}
+}
+
+// formatValue formats a scalar value the way it's sent over http
+func formatValue(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case float32:
+		return strconv.FormatFloat(float64(v), 'f', -1, 32)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case fmt.Stringer:
+		return v.String()
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// expandPath substitutes the values of the path parameters in a path
+func expandPath(path string, values map[string]string) string {
+	for name, value := range values {
+		path = strings.ReplaceAll(path, "{"+name+"}", url.PathEscape(value))
+	}
+	return path
+}
+
+// HTTPRequest turns the stub into an http request to a base url (eg. http://localhost:8080),
+// the body is encoded in the content type of the stub
+func (r *Request) HTTPRequest(baseURL string) (*http.Request, error) {
+	target, err := url.Parse(strings.TrimSuffix(baseURL, "/") + r.Path)
+	if err != nil {
+		return nil, err
+	}
+	target.RawQuery = r.Query.Encode()
+
+	body, contentType, err := r.encode()
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequest(r.Method, target.String(), body)
+	if err != nil {
+		return nil, err
+	}
+	for name, values := range r.Header {
+		req.Header[name] = append([]string(nil), values...)
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	return req, nil
+}
+
+// encode encodes the body or the form of the stub and returns the content type for it
+func (r *Request) encode() (io.Reader, string, error) {
+	mediaType, _, _ := mime.ParseMediaType(r.ContentType)
+	switch {
+	case mediaType == multipartMime || len(r.Files) > 0:
+		var buf bytes.Buffer
+		w := multipart.NewWriter(&buf)
+		if err := writeMultipart(w, r.Form, r.Files); err != nil {
+			return nil, "", err
+		}
+		return &buf, w.FormDataContentType(), nil
+	case len(r.Form) > 0 || mediaType == urlencodedMime:
+		return strings.NewReader(r.Form.Encode()), urlencodedMime, nil
+	case r.Body == nil:
+		return nil, "", nil
+	default:
+		contentType := r.ContentType
+		if contentType == "" {
+			contentType = jsonMime
+		}
+		data, err := encodeValue(r.Body, contentType)
+		if err != nil {
+			return nil, "", fmt.Errorf("unable to encode the body of %s: %v", r.OperationID, err)
+		}
+		return bytes.NewReader(data), contentType, nil
+	}
+}
+
+// encodable returns true when a value can be encoded in the media type, values are encoded as json or as text
+func encodable(mediaType string) bool {
+	mt, _, err := mime.ParseMediaType(mediaType)
+	return err == nil && (mt == jsonMime || strings.HasSuffix(mt, "+json") || strings.HasPrefix(mt, "text/"))
+}
+
+// encodeValue encodes a value in a media type, a value is encoded as json or as text
+func encodeValue(value interface{}, mediaType string) ([]byte, error) {
+	mt, _, err := mime.ParseMediaType(mediaType)
+	switch {
+	case err != nil:
+		return nil, err
+	case mt == jsonMime || strings.HasSuffix(mt, "+json"):
+		return json.Marshal(value)
+	case strings.HasPrefix(mt, "text/"):
+		return []byte(formatValue(value)), nil
+	default:
+		return nil, fmt.Errorf("unable to encode a value as %s", mediaType)
+	}
+}
+
+func writeMultipart(w *multipart.Writer, form url.Values, files map[string][]byte) error {
+	names := make([]string, 0, len(form))
+	for name := range form {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		for _, value := range form[name] {
+			if err := w.WriteField(name, value); err != nil {
+				return err
+			}
+		}
+	}
+
+	names = names[:0]
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		part, err := w.CreateFormFile(name, name)
+		if err != nil {
+			return err
+		}
+		if _, err := part.Write(files[name]); err != nil {
+			return err
+		}
+	}
+	return w.Close()
+}
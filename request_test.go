@@ -0,0 +1,121 @@
+package stubs
+
+import (
+	"encoding/json"
+	"io"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/go-openapi/loads"
+	"github.com/go-openapi/strfmt"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGenerator_GenRequest(t *testing.T) {
+	doc, err := loads.Spec("fixtures/petstore.yaml")
+	if !assert.NoError(t, err) {
+		return
+	}
+	gen := NewGenerator(doc)
+	gen.Verify = true
+
+	for i := 0; i < 20; i++ {
+		req, err := gen.GenRequest("listPets")
+		if !assert.NoError(t, err) {
+			return
+		}
+		assert.Equal(t, "GET", req.Method)
+		assert.Equal(t, "/api/pets", req.Path)
+		assert.Empty(t, req.ContentType)
+		if limit := req.Query.Get("limit"); limit != "" {
+			n, err := strconv.Atoi(limit)
+			assert.NoError(t, err)
+			assert.True(t, n >= 1 && n <= 100)
+		}
+		assert.True(t, len(req.Query["tags"]) <= 1, "csv tags are a single value")
+		for _, status := range req.Query["status"] {
+			assert.Contains(t, []string{"available", "pending", "sold"}, status)
+		}
+		if id := req.Header.Get("X-Request-Id"); id != "" {
+			assert.True(t, strfmt.IsUUID(id))
+		}
+	}
+
+	req, err := gen.GenRequestFor("get", "/pets/{petId}")
+	if assert.NoError(t, err) {
+		assert.Equal(t, "getPet", req.OperationID)
+		assert.Regexp(t, `^/api/pets/\d+$`, req.Path)
+	}
+
+	req, err = gen.GenRequest("createPet")
+	if assert.NoError(t, err) {
+		assert.Equal(t, "application/json", req.ContentType)
+		pet := req.Body.(map[string]interface{})
+		assert.NotEmpty(t, pet["name"])
+
+		httpReq, err := req.HTTPRequest("http://localhost:8080/")
+		if assert.NoError(t, err) {
+			assert.Equal(t, "http://localhost:8080/api/pets", httpReq.URL.String())
+			assert.Equal(t, "application/json", httpReq.Header.Get("Content-Type"))
+			var body map[string]interface{}
+			if assert.NoError(t, json.NewDecoder(httpReq.Body).Decode(&body)) {
+				assert.Equal(t, pet["name"], body["name"])
+			}
+		}
+	}
+
+	req, err = gen.GenRequest("uploadPhoto")
+	if assert.NoError(t, err) {
+		assert.Equal(t, "multipart/form-data", req.ContentType)
+		assert.NotEmpty(t, req.Files["photo"])
+		httpReq, err := req.HTTPRequest("http://localhost:8080")
+		if assert.NoError(t, err) && assert.NoError(t, httpReq.ParseMultipartForm(1<<20)) {
+			file, _, err := httpReq.FormFile("photo")
+			if assert.NoError(t, err) {
+				content, _ := io.ReadAll(file)
+				assert.Equal(t, req.Files["photo"], content)
+			}
+			assert.Equal(t, req.Form.Get("caption"), httpReq.FormValue("caption"))
+		}
+
+		// the content of the files is encoded as text
+		data, err := json.Marshal(req)
+		if assert.NoError(t, err) {
+			var encoded map[string]interface{}
+			if assert.NoError(t, json.Unmarshal(data, &encoded)) {
+				assert.Equal(t, "POST", encoded["method"])
+				assert.Equal(t, map[string]interface{}{"photo": string(req.Files["photo"])}, encoded["files"])
+				assert.Equal(t, "multipart/form-data", encoded["contentType"])
+			}
+		}
+	}
+
+	// the stub mode applies to a parameter which can violate it, modes for locations apply to the parameter at /{in}/{name}
+	gen.Mode = InvalidMaximum
+	for i := 0; i < 10; i++ {
+		req, err := gen.GenRequest("listPets")
+		if assert.NoError(t, err) {
+			n, err := strconv.Atoi(req.Query.Get("limit"))
+			assert.NoError(t, err)
+			assert.True(t, n > 100)
+		}
+	}
+	_, err = gen.GenRequest("getPet")
+	assert.Error(t, err)
+
+	gen.Mode, gen.Modes = Valid, map[string]StubMode{"/body/name": InvalidMaxLength, "/query/limit": InvalidMinimum}
+	req, err = gen.GenRequest("createPet")
+	if assert.NoError(t, err) {
+		assert.True(t, len(req.Body.(map[string]interface{})["name"].(string)) > 40)
+	}
+	req, err = gen.GenRequest("listPets")
+	if assert.NoError(t, err) {
+		assert.True(t, strings.HasPrefix(req.Query.Get("limit"), "-") || req.Query.Get("limit") == "0")
+	}
+
+	_, err = gen.GenRequest("missing")
+	assert.Error(t, err)
+	_, err = new(Generator).GenRequest("listPets")
+	assert.Error(t, err)
+}
@@ -0,0 +1,191 @@
+package stubs
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"github.com/go-openapi/spec"
+)
+
+// maxRefs is the number of references a schema can go through before it's considered a circular reference
+const maxRefs = 100
+
+// defaultMaxDepth is the number of times a schema can be nested in itself when the generator doesn't configure it
+const defaultMaxDepth = 3
+
+// resolver resolves schema references in a document and the documents it refers to.
+// The resolved schemas are cached, so the same reference isn't resolved again for every value.
+type resolver struct {
+	root *spec.Swagger
+	// origin is the base path as it was configured, basePath is its absolute path
+	origin   string
+	basePath string
+
+	lock  sync.RWMutex
+	cache map[string]resolvedSchema
+	// extends has the names of the definitions in the document which extend a schema with allOf, keyed by reference
+	extends map[string][]string
+	// extending has the references of the definitions which extend another schema
+	extending map[string]bool
+}
+
+// resolvedSchema is a schema without a reference, the location of the document it's defined in
+// and the last reference that was followed to find it.
+// A value for the schema also has to match the patterns, when allOf merged schemas with different patterns.
+type resolvedSchema struct {
+	schema   *spec.Schema
+	base     string
+	ref      string
+	patterns []string
+}
+
+func newResolver(root *spec.Swagger, basePath string) *resolver {
+	origin := basePath
+	if basePath != "" {
+		if abs, err := filepath.Abs(basePath); err == nil {
+			basePath = abs
+		}
+	}
+	return &resolver{
+		root:     root,
+		origin:   origin,
+		basePath: basePath,
+		cache:    make(map[string]resolvedSchema),
+	}
+}
+
+// resolve follows the references of a schema until it finds a schema without a reference.
+// The base is the location of the document the schema is defined in.
+func (r *resolver) resolve(schema *spec.Schema, base string) (resolvedSchema, error) {
+	if base == "" {
+		base = r.basePath
+	}
+
+	result := resolvedSchema{schema: schema, base: base}
+	for i := 0; result.schema.Ref.String() != ""; i++ {
+		if i == maxRefs {
+			return resolvedSchema{}, fmt.Errorf("circular reference %s", schema.Ref.String())
+		}
+
+		ref, refBase, err := r.absolute(result.schema.Ref, result.base)
+		if err != nil {
+			return resolvedSchema{}, err
+		}
+
+		key := ref.String()
+		r.lock.RLock()
+		cached, ok := r.cache[key]
+		r.lock.RUnlock()
+		if !ok {
+			resolved, err := spec.ResolveRefWithBase(r.root, &ref, &spec.ExpandOptions{RelativeBase: r.basePath})
+			if err != nil {
+				return resolvedSchema{}, fmt.Errorf("unable to resolve %s: %v", result.schema.Ref.String(), err)
+			}
+			cached = resolvedSchema{schema: resolved, base: refBase, ref: key}
+			r.lock.Lock()
+			r.cache[key] = cached
+			r.lock.Unlock()
+		}
+		result = cached
+	}
+	return result, nil
+}
+
+// absolute returns the reference relative to the location of the document it's defined in,
+// references in the root document which only have a fragment are resolved against the root document
+func (r *resolver) absolute(ref spec.Ref, base string) (spec.Ref, string, error) {
+	if ref.HasFragmentOnly && base == r.basePath {
+		return ref, base, nil
+	}
+
+	baseRef, err := spec.NewRef(base)
+	if err != nil {
+		return spec.Ref{}, "", err
+	}
+	abs, err := baseRef.Inherits(ref)
+	if err != nil {
+		return spec.Ref{}, "", err
+	}
+	return *abs, abs.RemoteURI(), nil
+}
+
+// subtypes returns the names of the definitions in the document which extend the schema for a reference,
+// directly or through other definitions, in alphabetical order.
+// It also returns true when the schema itself extends another schema.
+func (r *resolver) subtypes(key string) ([]string, bool) {
+	r.lock.Lock()
+	if r.extends == nil {
+		r.extends = make(map[string][]string)
+		r.extending = make(map[string]bool)
+		for name, def := range r.root.Definitions {
+			for _, branch := range def.AllOf {
+				if branch.Ref.String() == "" {
+					continue
+				}
+				if ref, _, err := r.absolute(branch.Ref, r.basePath); err == nil {
+					r.extends[ref.String()] = append(r.extends[ref.String()], name)
+					r.extending[definitionRef(name)] = true
+				}
+			}
+		}
+	}
+	r.lock.Unlock()
+
+	var names []string
+	seen := map[string]bool{key: true}
+	for pending := []string{key}; len(pending) > 0; pending = pending[1:] {
+		for _, name := range r.extends[pending[0]] {
+			child := definitionRef(name)
+			if !seen[child] {
+				seen[child] = true
+				names = append(names, name)
+				pending = append(pending, child)
+			}
+		}
+	}
+	sort.Strings(names)
+	return names, r.extending[key]
+}
+
+// definitionRef returns the reference to a definition in the document
+func definitionRef(name string) string {
+	return "#/definitions/" + pointerEscaper.Replace(name)
+}
+
+// store replaces the cached schema for a reference
+func (r *resolver) store(key string, resolved resolvedSchema) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.cache[key] = resolved
+}
+
+// resolve follows the references of a schema and merges the subschemas of allOf into a single schema.
+// A merged schema for a reference is cached in place of the schema it was merged from.
+func (g *generators) resolve(schema *spec.Schema, base string) (resolvedSchema, error) {
+	resolved, err := g.follow(schema, base)
+	if err != nil || resolved.schema == nil || len(resolved.schema.AllOf) == 0 {
+		return resolved, err
+	}
+
+	merged, err := g.allOf(resolved, nil)
+	if err != nil {
+		return resolvedSchema{}, err
+	}
+	if merged.ref != "" {
+		g.resolver.store(merged.ref, merged)
+	}
+	return merged, nil
+}
+
+// follow follows the references of a schema, references can only be resolved by generators for a document
+func (g *generators) follow(schema *spec.Schema, base string) (resolvedSchema, error) {
+	if schema == nil || schema.Ref.String() == "" {
+		return resolvedSchema{schema: schema, base: base}, nil
+	}
+	if g == nil || g.resolver == nil {
+		return resolvedSchema{}, fmt.Errorf("unable to resolve %s without a document", schema.Ref.String())
+	}
+	return g.resolver.resolve(schema, base)
+}
@@ -0,0 +1,103 @@
+package stubs
+
+import (
+	"testing"
+
+	"github.com/go-openapi/loads"
+	"github.com/go-openapi/spec"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGenerator_GenSchemaRefs(t *testing.T) {
+	doc, err := loads.Spec("fixtures/petstore.yaml")
+	if !assert.NoError(t, err) {
+		return
+	}
+	gen := NewGenerator(doc)
+
+	pet := spec.RefSchema("#/definitions/Pet")
+	for i := 0; i < 20; i++ {
+		res, err := gen.GenSchema("Pet", pet)
+		if assert.NoError(t, err) {
+			value := res.(map[string]interface{})
+			assert.IsType(t, int64(0), value["id"])
+			assert.IsType(t, "", value["name"])
+
+			// the owner is defined in another file, which refers to an address in that file
+			if owner, ok := value["owner"].(map[string]interface{}); ok {
+				assert.Contains(t, owner["email"], "@")
+				assert.NotEmpty(t, owner["address"].(map[string]interface{})["city"])
+			}
+			if tags, ok := value["tags"].([]interface{}); ok {
+				for _, tag := range tags {
+					assert.NotEmpty(t, tag.(map[string]interface{})["name"])
+				}
+			}
+		}
+	}
+
+	assert.Len(t, gen.resolved.cache, 4)
+
+	_, err = gen.GenSchema("Missing", spec.RefSchema("#/definitions/Missing"))
+	assert.Error(t, err)
+
+	_, err = new(Generator).GenSchema("Pet", pet)
+	assert.Error(t, err)
+}
+
+func TestGenerator_GenSchemaRecursion(t *testing.T) {
+	doc := &spec.Swagger{SwaggerProps: spec.SwaggerProps{Definitions: spec.Definitions{
+		"TreeNode": *new(spec.Schema).Typed("object", "").
+			WithRequired("name", "children").
+			SetProperty("name", *spec.StringProperty()).
+			SetProperty("children", *spec.ArrayProperty(spec.RefSchema("#/definitions/TreeNode"))),
+		"Person": *new(spec.Schema).Typed("object", "").
+			WithRequired("name").
+			SetProperty("name", *spec.StringProperty()).
+			SetProperty("manager", *spec.RefSchema("#/definitions/Person")),
+		"Employee": *new(spec.Schema).Typed("object", "").
+			WithRequired("manager").
+			SetProperty("manager", *spec.RefSchema("#/definitions/Employee")),
+	}}}
+	gen := &Generator{Spec: doc, MaxDepth: 2}
+
+	var depth func(value interface{}, key string) int
+	depth = func(value interface{}, key string) int {
+		var deepest int
+		switch v := value.(type) {
+		case map[string]interface{}:
+			deepest = depth(v[key], key) + 1
+		case []interface{}:
+			for _, member := range v {
+				if d := depth(member, key); d > deepest {
+					deepest = d
+				}
+			}
+		}
+		return deepest
+	}
+
+	for i := 0; i < 20; i++ {
+		tree, err := gen.GenSchema("tree", spec.RefSchema("#/definitions/TreeNode"))
+		if assert.NoError(t, err) {
+			assert.True(t, depth(tree, "children") <= 2)
+		}
+
+		person, err := gen.GenSchema("person", spec.RefSchema("#/definitions/Person"))
+		if assert.NoError(t, err) {
+			assert.True(t, depth(person, "manager") <= 2)
+		}
+	}
+
+	gen.MaxDepth = 0
+	tree, err := gen.GenSchema("tree", spec.RefSchema("#/definitions/TreeNode"))
+	if assert.NoError(t, err) {
+		assert.True(t, depth(tree, "children") <= defaultMaxDepth)
+	}
+
+	_, err = gen.GenSchema("employee", spec.RefSchema("#/definitions/Employee"))
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "#/definitions/Employee -> #/definitions/Employee")
+		assert.Contains(t, err.Error(), "/manager/manager/manager")
+	}
+}
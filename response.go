@@ -0,0 +1,224 @@
+package stubs
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/go-openapi/spec"
+)
+
+// Response is a stub for a response of an operation, with the headers serialized as they're sent over http
+type Response struct {
+	OperationID string `json:"operationId"`
+
+	// StatusCode of the response, the default response of an operation has the status code it was generated for
+	// or 0 when it's enumerated with the other responses
+	StatusCode int `json:"statusCode"`
+	// Default is true for the default response of the operation
+	Default bool `json:"default,omitempty"`
+
+	Header http.Header `json:"header,omitempty"`
+
+	// Body is the value for the schema of the response, nil when the response has no schema
+	Body interface{} `json:"body"`
+
+	// ContentType is the media type for the body, picked from the media types the operation produces
+	ContentType string `json:"contentType,omitempty"`
+}
+
+// GenResponse generates a response stub for the operation with the id and a status code,
+// the default response of the operation is generated for a status code the operation doesn't declare.
+// The status code 0 asks for the default response, like GenDefaultResponse does.
+func (s *Generator) GenResponse(operationID string, statusCode int) (*Response, error) {
+	analyzed, err := s.analyzer()
+	if err != nil {
+		return nil, err
+	}
+	_, _, operation, ok := analyzed.OperationForName(operationID)
+	if !ok {
+		return nil, fmt.Errorf("no operation found with id %s", operationID)
+	}
+	return s.genResponse(operation, statusCode, analyzed.ProducesFor(operation), Valid)
+}
+
+// GenDefaultResponse generates a stub for the default response of the operation with the id, its status code is 0.
+// It fails when the operation has no default response.
+func (s *Generator) GenDefaultResponse(operationID string) (*Response, error) {
+	analyzed, err := s.analyzer()
+	if err != nil {
+		return nil, err
+	}
+	_, _, operation, ok := analyzed.OperationForName(operationID)
+	if !ok {
+		return nil, fmt.Errorf("no operation found with id %s", operationID)
+	}
+	if operation.Responses == nil || operation.Responses.Default == nil {
+		return nil, fmt.Errorf("operation %s has no default response", operationID)
+	}
+	return s.genResponse(operation, 0, analyzed.ProducesFor(operation), Valid)
+}
+
+// GenResponses generates a response stub for every response the operation with the id declares,
+// in the order of their status codes with the default response last
+func (s *Generator) GenResponses(operationID string) ([]*Response, error) {
+	analyzed, err := s.analyzer()
+	if err != nil {
+		return nil, err
+	}
+	_, _, operation, ok := analyzed.OperationForName(operationID)
+	if !ok {
+		return nil, fmt.Errorf("no operation found with id %s", operationID)
+	}
+	if operation.Responses == nil {
+		return nil, nil
+	}
+
+	codes := make([]int, 0, len(operation.Responses.StatusCodeResponses)+1)
+	for code := range operation.Responses.StatusCodeResponses {
+		codes = append(codes, code)
+	}
+	sort.Ints(codes)
+	if operation.Responses.Default != nil {
+		codes = append(codes, 0)
+	}
+
+	responses := make([]*Response, 0, len(codes))
+	for _, code := range codes {
+		resp, err := s.genResponse(operation, code, analyzed.ProducesFor(operation), Valid)
+		if err != nil {
+			return nil, err
+		}
+		responses = append(responses, resp)
+	}
+	return responses, nil
+}
+
+// genResponse generates the headers and body of the response of an operation for a status code, 0 is the default response.
+// The stub mode of the generator applies to the body or a random header which can violate it,
+// the modes for locations apply to the body at /body and the headers at /header/{name}.
+// The body mode applies to the body on top of the modes of the generator.
+func (s *Generator) genResponse(operation *spec.Operation, statusCode int, produces []string, bodyMode StubMode) (*Response, error) {
+	declared, isDefault, err := s.declaredResponse(operation, statusCode)
+	if err != nil {
+		return nil, err
+	}
+	if bodyMode != Valid && declared.Schema == nil {
+		return nil, fmt.Errorf("response %s of operation %s has no body which can be %s", describeStatus(statusCode, isDefault), operation.ID, describeMode(bodyMode))
+	}
+	generator, err := s.generators()
+	if err != nil {
+		return nil, err
+	}
+
+	var names, paths []string
+	if declared.Schema != nil {
+		paths = append(paths, "/body")
+	}
+	for name := range declared.Headers {
+		names = append(names, name)
+	}
+	// the headers are generated in a fixed order, so the same source produces the same response
+	sort.Strings(names)
+	for _, name := range names {
+		paths = append(paths, "/header/"+pointerEscaper.Replace(name))
+	}
+
+	description := "header or body of response " + describeStatus(statusCode, isDefault) + " of operation " + operation.ID
+	options := func(i int, targets modeTargets) (GeneratorOpts, error) {
+		if declared.Schema != nil {
+			if i == 0 {
+				return generator.schemaOpts("body", declared.Schema, paths[i], targets)
+			}
+			i--
+		}
+		header := declared.Headers[names[i]]
+		return generator.headerOpts(names[i], &header, "/header/"+pointerEscaper.Replace(names[i]), targets)
+	}
+	targets, err := s.assignMode(generator, description, paths, options)
+	if err != nil {
+		return nil, err
+	}
+	if bodyMode != Valid {
+		targets["/body"] |= bodyMode
+	}
+
+	resp := &Response{
+		OperationID: operation.ID,
+		StatusCode:  statusCode,
+		Default:     isDefault,
+		Header:      make(http.Header),
+	}
+	for i, path := range paths {
+		opts, err := options(i, targets)
+		if err != nil {
+			return nil, err
+		}
+		if path == "/body" {
+			check := generator.checkSchema("body", declared.Schema, opts.(*schemaOpts).schema)
+			if resp.Body, err = s.generate(generator, opts, "body of response "+describeStatus(statusCode, isDefault), targets.under(path), check); err != nil {
+				return nil, err
+			}
+			resp.ContentType = negotiate(produces, jsonMime)
+			continue
+		}
+
+		name := opts.FieldName()
+		header := declared.Headers[name]
+		value, err := s.generate(generator, opts, "header ["+name+"]", targets.under(path), checkHeader(name, &header))
+		if err != nil {
+			return nil, err
+		}
+		if value != nil {
+			resp.Header.Set(name, strings.Join(serialize(value, header.CollectionFormat, header.Items), ","))
+		}
+	}
+	return resp, nil
+}
+
+// declaredResponse returns the response the operation declares for a status code, or its default response.
+// It also returns true when it's the default response.
+func (s *Generator) declaredResponse(operation *spec.Operation, statusCode int) (*spec.Response, bool, error) {
+	if operation.Responses == nil {
+		return nil, false, fmt.Errorf("operation %s has no responses", operation.ID)
+	}
+
+	declared, ok := operation.Responses.StatusCodeResponses[statusCode]
+	isDefault := !ok
+	if isDefault {
+		if operation.Responses.Default == nil {
+			return nil, false, fmt.Errorf("operation %s has no response for status code %d and no default response", operation.ID, statusCode)
+		}
+		declared = *operation.Responses.Default
+	}
+	if declared.Ref.String() == "" {
+		return &declared, isDefault, nil
+	}
+
+	resolved, err := spec.ResolveResponseWithBase(s.Spec, declared.Ref, &spec.ExpandOptions{RelativeBase: s.resolver().basePath})
+	if err != nil {
+		return nil, false, fmt.Errorf("unable to resolve %s: %v", declared.Ref.String(), err)
+	}
+	return resolved, isDefault, nil
+}
+
+// headerOpts returns the options for a response header located at the path
+func (g *generators) headerOpts(key string, header *spec.Header, path string, targets modeTargets) (*simpleOpts, error) {
+	gopts, err := headerGenOpts(key, header)
+	if err != nil {
+		return nil, err
+	}
+	gopts.locate(path, targets)
+	gopts.gens = g
+	return gopts, nil
+}
+
+// describeStatus names a response for error messages
+func describeStatus(statusCode int, isDefault bool) string {
+	if isDefault {
+		return "default"
+	}
+	return strconv.Itoa(statusCode)
+}
@@ -0,0 +1,103 @@
+package stubs
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/go-openapi/loads"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGenerator_GenResponse(t *testing.T) {
+	doc, err := loads.Spec("fixtures/petstore.yaml")
+	if !assert.NoError(t, err) {
+		return
+	}
+	gen := NewGenerator(doc)
+	gen.Verify = true
+
+	for i := 0; i < 20; i++ {
+		resp, err := gen.GenResponse("listPets", 200)
+		if !assert.NoError(t, err) {
+			return
+		}
+		assert.Equal(t, 200, resp.StatusCode)
+		assert.False(t, resp.Default)
+		assert.Equal(t, "application/json", resp.ContentType)
+		assert.IsType(t, []interface{}{}, resp.Body)
+		limit, err := strconv.Atoi(resp.Header.Get("X-Rate-Limit"))
+		if assert.NoError(t, err) {
+			assert.True(t, limit >= 0 && limit <= 1000)
+		}
+	}
+
+	// an undeclared status code gets the default response, which is a reference to a response
+	resp, err := gen.GenResponse("listPets", 503)
+	if assert.NoError(t, err) {
+		assert.Equal(t, 503, resp.StatusCode)
+		assert.True(t, resp.Default)
+		body := resp.Body.(map[string]interface{})
+		assert.IsType(t, int32(0), body["code"])
+		assert.IsType(t, "", body["message"])
+	}
+	_, err = gen.GenResponse("getPet", 503)
+	assert.Error(t, err)
+
+	// the default response is generated for the status code 0
+	resp, err = gen.GenDefaultResponse("listPets")
+	if assert.NoError(t, err) {
+		assert.Equal(t, 0, resp.StatusCode)
+		assert.True(t, resp.Default)
+		assert.Equal(t, "application/json", resp.ContentType)
+		assert.Contains(t, resp.Body, "code")
+	}
+	resp, err = gen.GenResponse("listPets", 0)
+	if assert.NoError(t, err) {
+		assert.Equal(t, 0, resp.StatusCode)
+		assert.True(t, resp.Default)
+	}
+	_, err = gen.GenDefaultResponse("getPet")
+	assert.Error(t, err)
+	_, err = gen.GenDefaultResponse("missing")
+	assert.Error(t, err)
+
+	responses, err := gen.GenResponses("createPet")
+	if assert.NoError(t, err) && assert.Len(t, responses, 2) {
+		assert.Equal(t, 201, responses[0].StatusCode)
+		assert.Equal(t, 422, responses[1].StatusCode)
+		assert.Contains(t, responses[1].Body, "message")
+	}
+	responses, err = gen.GenResponses("listPets")
+	if assert.NoError(t, err) && assert.Len(t, responses, 2) {
+		assert.Equal(t, 200, responses[0].StatusCode)
+		assert.Equal(t, 0, responses[1].StatusCode)
+		assert.True(t, responses[1].Default)
+	}
+	responses, err = gen.GenResponses("deletePet")
+	if assert.NoError(t, err) && assert.Len(t, responses, 1) {
+		assert.Nil(t, responses[0].Body)
+		assert.Empty(t, responses[0].ContentType)
+	}
+
+	// the stub mode applies to the body or a header which can violate it, modes for locations apply to /body and /header/{name}
+	gen.Mode = InvalidMaximum
+	for i := 0; i < 10; i++ {
+		resp, err := gen.GenResponse("listPets", 200)
+		if assert.NoError(t, err) {
+			limit, err := strconv.Atoi(resp.Header.Get("X-Rate-Limit"))
+			assert.NoError(t, err)
+			assert.True(t, limit > 1000)
+		}
+	}
+	_, err = gen.GenResponse("getPet", 200)
+	assert.Error(t, err)
+
+	gen.Mode, gen.Modes = Valid, map[string]StubMode{"/body/name": InvalidMinLength}
+	resp, err = gen.GenResponse("getPet", 200)
+	if assert.NoError(t, err) {
+		assert.Empty(t, resp.Body.(map[string]interface{})["name"])
+	}
+
+	_, err = gen.GenResponses("missing")
+	assert.Error(t, err)
+}
@@ -0,0 +1,109 @@
+package stubs
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// StatusHeader asks the mock for the response of the operation with a status code (eg. X-Stub-Status: 404)
+	StatusHeader = "X-Stub-Status"
+	// ModeHeader asks the mock for a response with a body generated for a stub mode (eg. X-Stub-Mode: maxLength|pattern)
+	ModeHeader = "X-Stub-Mode"
+	// DelayHeader asks the mock to delay the response by a duration or a number of milliseconds (eg. X-Stub-Delay: 250ms)
+	DelayHeader = "X-Stub-Delay"
+)
+
+// the query flags ask for a scenario like the control headers, for clients which can't send headers
+const (
+	statusFlag = "__code"
+	modeFlag   = "__mode"
+	delayFlag  = "__delay"
+)
+
+// scenario is the response a request asks the mock for
+type scenario struct {
+	// status is the status code of the response, 0 is the success response of the operation
+	status int
+	mode   StubMode
+	delay  time.Duration
+	// delayed is true when the request asks for a delay, which replaces the latency of the mock
+	delayed bool
+}
+
+// parseScenario parses the scenario a request asks for with its control headers, the code preference of its Prefer header
+// and its query flags. A control header takes precedence over a preference, which takes precedence over a query flag.
+func parseScenario(r *http.Request) (scenario, error) {
+	var sc scenario
+	query := r.URL.Query()
+
+	if value := controlValue(r.Header.Get(StatusHeader), preference(r.Header.Values("Prefer"), "code"), query.Get(statusFlag)); value != "" {
+		status, err := strconv.Atoi(value)
+		if err != nil || status < 100 || status > 599 {
+			return sc, fmt.Errorf("invalid status code %q for the response", value)
+		}
+		sc.status = status
+	}
+
+	if value := controlValue(r.Header.Get(ModeHeader), query.Get(modeFlag)); value != "" {
+		mode, err := ParseStubMode(value)
+		if err != nil {
+			return sc, fmt.Errorf("invalid stub mode for the response: %v", err)
+		}
+		sc.mode = mode
+	}
+
+	if value := controlValue(r.Header.Get(DelayHeader), query.Get(delayFlag)); value != "" {
+		delay, err := time.ParseDuration(value)
+		if ms, e := strconv.Atoi(value); e == nil {
+			delay, err = time.Duration(ms)*time.Millisecond, nil
+		}
+		if err != nil || delay < 0 {
+			return sc, fmt.Errorf("invalid delay %q for the response", value)
+		}
+		sc.delay, sc.delayed = delay, true
+	}
+	return sc, nil
+}
+
+// controlValue returns the first value which is set
+func controlValue(values ...string) string {
+	for _, value := range values {
+		if value = strings.TrimSpace(value); value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+// preference returns the value of a preference in Prefer headers (eg. Prefer: code=404, dynamic=true)
+func preference(headers []string, name string) string {
+	for _, header := range headers {
+		for _, part := range strings.FieldsFunc(header, func(r rune) bool { return r == ',' || r == ';' }) {
+			kv := strings.SplitN(part, "=", 2)
+			if len(kv) == 2 && strings.EqualFold(strings.TrimSpace(kv[0]), name) {
+				return strings.Trim(strings.TrimSpace(kv[1]), `"`)
+			}
+		}
+	}
+	return ""
+}
+
+// sleep waits for a delay, it returns false when the context is done first
+func sleep(ctx context.Context, delay time.Duration) bool {
+	if delay <= 0 {
+		return true
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
@@ -0,0 +1,317 @@
+package stubs
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/go-openapi/spec"
+)
+
+// collection has the resources stored in a collection of a stateful mock, in the order they were stored.
+// The identifiers of deleted resources are kept, so a deleted resource isn't found rather than generated.
+type collection struct {
+	ids     []string
+	items   map[string]map[string]interface{}
+	deleted map[string]bool
+}
+
+func newCollection() *collection {
+	return &collection{
+		items:   make(map[string]map[string]interface{}),
+		deleted: make(map[string]bool),
+	}
+}
+
+// put stores a resource, a resource which was deleted is stored again
+func (c *collection) put(id string, item map[string]interface{}) {
+	if _, ok := c.items[id]; !ok {
+		c.ids = append(c.ids, id)
+	}
+	c.items[id] = item
+	delete(c.deleted, id)
+}
+
+// remove deletes a resource
+func (c *collection) remove(id string) {
+	c.deleted[id] = true
+	if _, ok := c.items[id]; !ok {
+		return
+	}
+	delete(c.items, id)
+	for i, stored := range c.ids {
+		if stored == id {
+			c.ids = append(c.ids[:i], c.ids[i+1:]...)
+			break
+		}
+	}
+}
+
+// taken returns true when a resource is stored or was deleted with the identifier
+func (c *collection) taken(id string) bool {
+	_, ok := c.items[id]
+	return ok || c.deleted[id]
+}
+
+// list returns the stored resources in the order they were stored
+func (c *collection) list() []interface{} {
+	items := make([]interface{}, 0, len(c.ids))
+	for _, id := range c.ids {
+		items = append(items, c.items[id])
+	}
+	return items
+}
+
+// collection returns the collection at the path of a request, the path has the values of the path parameters of parent resources
+func (m *Mock) collection(path string) *collection {
+	if m.store == nil {
+		m.store = make(map[string]*collection)
+	}
+	c, ok := m.store[path]
+	if !ok {
+		c = newCollection()
+		m.store[path] = c
+	}
+	return c
+}
+
+// serveResource answers a request for a collection or a resource from the store of the mock: a POST request to a collection and
+// a PUT request for a resource store the body, GET requests read or list resources and a DELETE request deletes a resource.
+// It returns false for a request which is answered with a generated response.
+func (m *Mock) serveResource(w http.ResponseWriter, r *http.Request, rt *route, mediaType string) bool {
+	if rt.resource == "" {
+		return false
+	}
+	segments, _ := m.segments(r.URL.Path)
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	if !rt.item {
+		c := m.collection(strings.Join(segments, "/"))
+		switch strings.ToUpper(r.Method) {
+		case http.MethodGet:
+			m.respond(w, rt, mediaType, func(body interface{}) interface{} { return listBody(body, c.list()) })
+			return true
+		case http.MethodPost:
+			return m.createResource(w, r, rt, mediaType, c)
+		}
+		return false
+	}
+
+	c := m.collection(strings.Join(segments[:len(segments)-1], "/"))
+	id := segments[len(segments)-1]
+	method := strings.ToUpper(r.Method)
+	if c.deleted[id] && (method == http.MethodGet || method == http.MethodDelete) {
+		m.notFound(w, rt, mediaType, id)
+		return true
+	}
+	switch method {
+	case http.MethodGet:
+		if item, ok := c.items[id]; ok {
+			m.respond(w, rt, mediaType, func(interface{}) interface{} { return item })
+			return true
+		}
+		// a resource which isn't stored is generated with the identifier of the request
+		m.respond(w, rt, mediaType, func(body interface{}) interface{} {
+			if obj, ok := body.(map[string]interface{}); ok {
+				if name := identifier(propertyNames(obj), rt.resource); name != "" {
+					obj[name] = rt.pathValue(id)
+				}
+			}
+			return body
+		})
+		return true
+	case http.MethodPut:
+		item, _, ok, err := m.resourceBody(r, rt, c.items[id])
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return true
+		}
+		if !ok {
+			return false
+		}
+		if name := identifier(propertyNames(item), rt.resource); name != "" {
+			item[name] = rt.pathValue(id)
+		}
+		c.put(id, item)
+		m.respond(w, rt, mediaType, func(interface{}) interface{} { return item })
+		return true
+	case http.MethodDelete:
+		c.remove(id)
+		m.respond(w, rt, mediaType, nil)
+		return true
+	}
+	return false
+}
+
+// createResource stores the body of a POST request to a collection, the identifier of the resource is generated when it isn't sent.
+// A generated identifier which is taken by a resource of the collection is generated again, an identifier which is sent replaces the resource.
+// It returns false when the body isn't a resource with an identifier.
+func (m *Mock) createResource(w http.ResponseWriter, r *http.Request, rt *route, mediaType string, c *collection) bool {
+	var item map[string]interface{}
+	var id string
+	for attempt := 0; attempt < maxAttempts; attempt++ {
+		var generated, ok bool
+		var err error
+		item, generated, ok, err = m.resourceBody(r, rt, nil)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return true
+		}
+		if !ok {
+			return false
+		}
+		name := identifier(propertyNames(item), rt.resource)
+		if name == "" || item[name] == nil {
+			return false
+		}
+		if id = formatValue(item[name]); !generated || !c.taken(id) {
+			break
+		}
+	}
+	c.put(id, item)
+	m.respond(w, rt, mediaType, func(interface{}) interface{} { return item })
+	return true
+}
+
+// resourceBody decodes the body of a request for a resource, it returns false when the body isn't an object for a schema.
+// The read only properties are taken from the stored resource or generated, the identifier is generated when it isn't sent.
+// It also returns true when the identifier is generated.
+func (m *Mock) resourceBody(r *http.Request, rt *route, stored map[string]interface{}) (map[string]interface{}, bool, bool, error) {
+	var schema *spec.Schema
+	for _, param := range rt.params {
+		if param.In == "body" {
+			schema = param.Schema
+		}
+	}
+	if schema == nil {
+		return nil, false, false, nil
+	}
+	value, err := decodeBody(r)
+	if err != nil {
+		return nil, false, false, err
+	}
+	item, ok := value.(map[string]interface{})
+	if !ok {
+		return nil, false, false, nil
+	}
+
+	generator, err := m.Generator.generators()
+	if err != nil {
+		return nil, false, false, err
+	}
+	opts, err := generator.schemaOpts("body", schema, "/body", make(modeTargets))
+	if err != nil {
+		return nil, false, false, err
+	}
+	value, err = m.Generator.generate(generator, opts, "body of resource", make(modeTargets), generator.checkSchema("body", schema, opts.schema))
+	if err != nil {
+		return nil, false, false, err
+	}
+	generated, _ := value.(map[string]interface{})
+
+	names := make([]string, 0, len(opts.schema.Properties))
+	for name := range opts.schema.Properties {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	id := identifier(names, rt.resource)
+	var generatedID bool
+	for _, name := range names {
+		prop := opts.schema.Properties[name]
+		resolved, err := generator.follow(&prop, opts.base)
+		if err != nil {
+			return nil, false, false, err
+		}
+		if !prop.ReadOnly && !resolved.schema.ReadOnly {
+			continue
+		}
+		delete(item, name)
+		if v, ok := stored[name]; ok {
+			item[name] = v
+		} else if v, ok := generated[name]; ok {
+			item[name] = v
+			generatedID = generatedID || name == id
+		}
+	}
+
+	if id != "" && item[id] == nil {
+		item[id] = generated[id]
+		generatedID = true
+	}
+	return item, generatedID, true, nil
+}
+
+// respond answers a request with the success response of the operation of the route, the body replaces the generated body
+// of a response which has a body
+func (m *Mock) respond(w http.ResponseWriter, rt *route, mediaType string, body func(generated interface{}) interface{}) {
+	resp, err := m.Generator.genResponse(rt.operation, successStatus(rt.operation), rt.produces, Valid)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if resp.Body != nil && body != nil {
+		resp.Body = body(resp.Body)
+	}
+	writeResponse(w, resp, mediaType)
+}
+
+// notFound answers a request for a deleted resource with the 404 response of the operation, or its default response as a 404
+func (m *Mock) notFound(w http.ResponseWriter, rt *route, mediaType, id string) {
+	message := fmt.Sprintf("%s %s is deleted", rt.resource, id)
+	if _, _, err := m.Generator.declaredResponse(rt.operation, http.StatusNotFound); err != nil {
+		http.Error(w, message, http.StatusNotFound)
+		return
+	}
+	resp, err := m.errorResponse(rt, http.StatusNotFound, Valid, message)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeResponse(w, resp, mediaType)
+}
+
+// pathValue returns the value of a path parameter of the route converted to the type of the parameter
+func (rt *route) pathValue(value string) interface{} {
+	for i := range rt.params {
+		param := &rt.params[i]
+		if param.In != "path" || param.Name != rt.resource {
+			continue
+		}
+		if parsed, err := parseParam(param, []string{value}); err == nil && parsed != nil {
+			return parsed
+		}
+	}
+	return value
+}
+
+// identifier returns the name of the property which identifies a resource, the property named like the path parameter
+// for the resources of the collection (eg. petId) or the property named id
+func identifier(names []string, param string) string {
+	for _, candidate := range []string{param, "id"} {
+		for _, name := range names {
+			if strings.EqualFold(name, candidate) {
+				return name
+			}
+		}
+	}
+	return ""
+}
+
+// listBody puts the resources of a collection in the body of a response, the body is the list or has the list
+// in its first array property
+func listBody(body interface{}, items []interface{}) interface{} {
+	switch b := body.(type) {
+	case []interface{}:
+		return items
+	case map[string]interface{}:
+		for _, name := range propertyNames(b) {
+			if _, ok := b[name].([]interface{}); ok {
+				b[name] = items
+				return b
+			}
+		}
+	}
+	return body
+}
@@ -0,0 +1,317 @@
+package stubs
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/go-openapi/errors"
+	"github.com/go-openapi/spec"
+)
+
+// defaultMaxMemory is the memory for the parts of a multipart form, larger parts are stored in temporary files
+const defaultMaxMemory = 32 << 20
+
+// messageFields are the names of the properties of an error body which get the validation messages of a rejected request,
+// in order of preference
+var messageFields = []string{"message", "msg", "detail", "details", "error", "description", "reason", "title"}
+
+// validateRequest validates the parameters and the body of a request against the parameters of the operation it's routed to,
+// it returns the validation errors of the request
+func (m *Mock) validateRequest(r *http.Request, rt *route) ([]error, error) {
+	generator, err := m.Generator.generators()
+	if err != nil {
+		return nil, err
+	}
+	segments, _ := m.segments(r.URL.Path)
+
+	var errs []error
+	for i := range rt.params {
+		param := &rt.params[i]
+		var values []string
+		switch param.In {
+		case "path":
+			values = rt.pathValues(param.Name, segments)
+		case "query":
+			values = r.URL.Query()[param.Name]
+		case "header":
+			values = r.Header.Values(param.Name)
+		case "formData":
+			if err := parseForm(r); err != nil {
+				errs = append(errs, errors.NewParseError(param.Name, param.In, "", err))
+				continue
+			}
+			if param.Type == "file" {
+				if param.Required && (r.MultipartForm == nil || len(r.MultipartForm.File[param.Name]) == 0) {
+					errs = append(errs, errors.Required(param.Name, param.In, nil))
+				}
+				continue
+			}
+			values = r.PostForm[param.Name]
+		case "body":
+			body, err := decodeBody(r)
+			if err != nil {
+				errs = append(errs, errors.NewParseError(param.Name, param.In, "", err))
+				continue
+			}
+			if missing, ok := requiredErrors(param.Name, param.In, param.Required, body); ok {
+				errs = append(errs, missing...)
+				continue
+			}
+			if param.Schema == nil {
+				continue
+			}
+			result, err := generator.validate(*param.Schema, "", body)
+			if err != nil {
+				return nil, err
+			}
+			errs = append(errs, result.Errors...)
+			continue
+		}
+
+		value, err := parseParam(param, values)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		invalid, err := checkParam(param)(value)
+		if err != nil {
+			return nil, err
+		}
+		errs = append(errs, invalid...)
+	}
+	return errs, nil
+}
+
+// reject answers a request which doesn't validate with the 400 or 422 response of the operation, or its default response as a 400.
+// The validation messages are embedded in the body of the response, an operation without these responses answers with the messages as text.
+// The body mode applies to the body of the response.
+func (m *Mock) reject(w http.ResponseWriter, rt *route, mediaType string, bodyMode StubMode, errs []error) {
+	statusCode, ok := errorStatus(rt.operation)
+	if !ok {
+		http.Error(w, describeErrors(errs), http.StatusBadRequest)
+		return
+	}
+
+	m.lock.Lock()
+	resp, err := m.errorResponse(rt, statusCode, bodyMode, describeErrors(errs))
+	m.lock.Unlock()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeResponse(w, resp, mediaType)
+}
+
+// errorResponse generates the response of the operation of a route for a status code with the messages in its body
+func (m *Mock) errorResponse(rt *route, statusCode int, bodyMode StubMode, messages string) (*Response, error) {
+	resp, err := m.Generator.genResponse(rt.operation, statusCode, rt.produces, bodyMode)
+	if err != nil {
+		return nil, err
+	}
+	properties, err := m.Generator.stringProperties(rt.operation, statusCode)
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = embedMessages(resp.Body, properties, messages)
+	return resp, nil
+}
+
+// stringProperties returns the names of the string properties of the body of the response of an operation for a status code,
+// in alphabetical order
+func (s *Generator) stringProperties(operation *spec.Operation, statusCode int) ([]string, error) {
+	declared, _, err := s.declaredResponse(operation, statusCode)
+	if err != nil || declared.Schema == nil {
+		return nil, err
+	}
+	generator, err := s.generators()
+	if err != nil {
+		return nil, err
+	}
+	resolved, err := generator.resolve(declared.Schema, "")
+	if err != nil || resolved.schema == nil {
+		return nil, err
+	}
+
+	var names []string
+	for name, prop := range resolved.schema.Properties {
+		prop := prop
+		property, err := generator.follow(&prop, resolved.base)
+		if err != nil {
+			return nil, err
+		}
+		if property.schema.Type.Contains("string") {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// pathValues returns the value of a path parameter in the segments of the path of a request
+func (rt *route) pathValues(name string, segments []string) []string {
+	for i, segment := range rt.segments {
+		if segment == "{"+name+"}" && i < len(segments) {
+			return []string{segments[i]}
+		}
+	}
+	return nil
+}
+
+// errorStatus returns the status code of the response of an operation for a request which doesn't validate,
+// the declared 400 or 422 response or the default response as a 400. It returns false when the operation has none of them.
+func errorStatus(operation *spec.Operation) (int, bool) {
+	if operation.Responses == nil {
+		return 0, false
+	}
+	for _, code := range []int{http.StatusBadRequest, http.StatusUnprocessableEntity} {
+		if _, ok := operation.Responses.StatusCodeResponses[code]; ok {
+			return code, true
+		}
+	}
+	return http.StatusBadRequest, operation.Responses.Default != nil
+}
+
+// embedMessages puts the validation messages in the body of an error response, in the first message field of an object
+// or as a string body. The message field is picked from the string properties of the schema of the body, so it's set
+// even when it isn't generated, or else from the string fields of the body. A body without a message field is left as it's generated.
+func embedMessages(body interface{}, properties []string, messages string) interface{} {
+	switch b := body.(type) {
+	case string:
+		return messages
+	case map[string]interface{}:
+		names := append([]string{}, properties...)
+		for _, name := range propertyNames(b) {
+			if _, ok := b[name].(string); ok {
+				names = append(names, name)
+			}
+		}
+		for _, field := range messageFields {
+			for _, name := range names {
+				if strings.EqualFold(name, field) {
+					b[name] = messages
+					return b
+				}
+			}
+		}
+	}
+	return body
+}
+
+// propertyNames returns the names of the properties of an object in alphabetical order
+func propertyNames(obj map[string]interface{}) []string {
+	names := make([]string, 0, len(obj))
+	for name := range obj {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// parseForm parses the form of a request, as multipart form data or as an url encoded form
+func parseForm(r *http.Request) error {
+	if err := r.ParseMultipartForm(defaultMaxMemory); err != nil && err != http.ErrNotMultipart {
+		return err
+	}
+	return nil
+}
+
+// decodeBody decodes the body of a request in its content type, json is decoded to values and text is a string.
+// An empty body is nil and a body without a content type is json. The body of the request can be decoded again.
+func decodeBody(r *http.Request) (interface{}, error) {
+	if r.Body == nil {
+		return nil, nil
+	}
+	data, err := io.ReadAll(r.Body)
+	if err != nil {
+		return nil, err
+	}
+	r.Body = io.NopCloser(bytes.NewReader(data))
+	if len(bytes.TrimSpace(data)) == 0 {
+		return nil, nil
+	}
+
+	contentType := r.Header.Get("Content-Type")
+	if contentType == "" {
+		contentType = jsonMime
+	}
+	mt, _, err := mime.ParseMediaType(contentType)
+	switch {
+	case err != nil:
+		return nil, err
+	case mt == jsonMime || strings.HasSuffix(mt, "+json"):
+		var value interface{}
+		if err := json.Unmarshal(data, &value); err != nil {
+			return nil, err
+		}
+		return value, nil
+	case strings.HasPrefix(mt, "text/"):
+		return string(data), nil
+	default:
+		return nil, fmt.Errorf("unable to decode a body in %s", mt)
+	}
+}
+
+// parseParam converts the values of a parameter as they're sent over http to the type of the parameter,
+// the members of a collection are split by its collection format. A parameter without a value is nil.
+func parseParam(param *spec.Parameter, values []string) (interface{}, error) {
+	if len(values) == 0 || (len(values) == 1 && values[0] == "" && !param.AllowEmptyValue) {
+		return nil, nil
+	}
+	return parseValues(param.Name, param.In, &param.SimpleSchema, param.Items, values)
+}
+
+// parseValues converts the values for a simple schema, the members of a collection are converted with the items
+func parseValues(name, in string, schema *spec.SimpleSchema, items *spec.Items, values []string) (interface{}, error) {
+	if schema.Type != "array" {
+		return parseValue(name, in, schema.Type, values[0])
+	}
+
+	if schema.CollectionFormat != "multi" {
+		sep := separator(schema.CollectionFormat)
+		joined := strings.Join(values, sep)
+		if joined == "" {
+			return []interface{}{}, nil
+		}
+		values = strings.Split(joined, sep)
+	}
+	members := make([]interface{}, 0, len(values))
+	for _, value := range values {
+		if items == nil {
+			members = append(members, value)
+			continue
+		}
+		member, err := parseValues(name, in, &items.SimpleSchema, items.Items, []string{value})
+		if err != nil {
+			return nil, err
+		}
+		members = append(members, member)
+	}
+	return members, nil
+}
+
+// parseValue converts a scalar value as it's sent over http to a type
+func parseValue(name, in, typeName, value string) (interface{}, error) {
+	var parsed interface{}
+	var err error
+	switch typeName {
+	case "integer":
+		parsed, err = strconv.ParseInt(value, 10, 64)
+	case "number":
+		parsed, err = strconv.ParseFloat(value, 64)
+	case "boolean":
+		parsed, err = strconv.ParseBool(value)
+	default:
+		return value, nil
+	}
+	if err != nil {
+		return nil, errors.InvalidType(name, in, typeName, value)
+	}
+	return parsed, nil
+}
@@ -0,0 +1,167 @@
+package stubs
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/go-openapi/errors"
+	"github.com/go-openapi/spec"
+	"github.com/go-openapi/strfmt"
+	"github.com/go-openapi/validate"
+)
+
+// defaultVerifyAttempts is the number of times a value is generated for verification when the generator doesn't configure it
+const defaultVerifyAttempts = 10
+
+// modeCodes are the codes of the validation errors for a value which violates the validation of a stub mode
+var modeCodes = map[StubMode]int32{
+	InvalidRequired:    errors.RequiredFailCode,
+	InvalidMaximum:     errors.MaxFailCode,
+	InvalidMinimum:     errors.MinFailCode,
+	InvalidMaxLength:   errors.TooLongFailCode,
+	InvalidMinLength:   errors.TooShortFailCode,
+	InvalidPattern:     errors.PatternFailCode,
+	InvalidMaxItems:    errors.MaxItemsFailCode,
+	InvalidMinItems:    errors.MinItemsFailCode,
+	InvalidUniqueItems: errors.UniqueFailCode,
+	InvalidMultipleOf:  errors.MultipleOfFailCode,
+	InvalidEnum:        errors.EnumFailCode,
+}
+
+// checker returns the validation errors of a generated value
+type checker func(value interface{}) ([]error, error)
+
+// verify generates a value and verifies it with the checker when the generator verifies its values,
+// the targets are the stub modes for the locations in the value.
+// A valid value has to pass validation and an invalid value has to fail it with the code of a selected validation,
+// when a value doesn't it's generated again up to the number of attempts of the generator.
+// The validators stop at the first violation of a value, so a value which violates several validations is only reported for one of them.
+func (s *Generator) verify(key string, targets modeTargets, generate func() (interface{}, error), check checker) (interface{}, error) {
+	if !s.Verify {
+		return generate()
+	}
+
+	var invalid bool
+	var expected StubMode
+	for _, mode := range targets {
+		invalid = invalid || mode != Valid
+		expected |= mode &^ Invalid
+	}
+	attempts := s.VerifyAttempts
+	if attempts <= 0 {
+		attempts = defaultVerifyAttempts
+	}
+
+	var problem string
+	for attempt := 0; attempt < attempts; attempt++ {
+		value, err := generate()
+		if err != nil {
+			return nil, err
+		}
+		errs, err := check(value)
+		if err != nil {
+			return nil, err
+		}
+
+		switch {
+		case !invalid && len(errs) == 0, invalid && len(errs) > 0 && (expected == Valid || reported(expected, errs)):
+			return value, nil
+		case !invalid:
+			problem = describeErrors(errs)
+		case len(errs) == 0:
+			problem = "the value passed validation"
+		default:
+			problem = fmt.Sprintf("the value failed validation for other reasons than %s: %s", expected, describeErrors(errs))
+		}
+	}
+
+	validity := "valid"
+	if invalid {
+		validity = "invalid"
+		if expected != Valid {
+			validity += " for " + expected.String()
+		}
+	}
+	return nil, fmt.Errorf("unable to verify a value for [%s] which is %s after %d attempts: %s", key, validity, attempts, problem)
+}
+
+// reported returns true when the errors report a violation of a validation selected by the mode
+func reported(mode StubMode, errs []error) bool {
+	codes := make(map[int32]bool)
+	collectCodes(errs, codes)
+	for _, m := range splitModes(mode) {
+		if codes[modeCodes[m]] {
+			return true
+		}
+	}
+	return false
+}
+
+func collectCodes(errs []error, codes map[int32]bool) {
+	for _, err := range errs {
+		switch e := err.(type) {
+		case *errors.CompositeError:
+			collectCodes(e.Errors, codes)
+		case errors.Error:
+			codes[e.Code()] = true
+		}
+	}
+}
+
+func describeErrors(errs []error) string {
+	msgs := make([]string, 0, len(errs))
+	for _, err := range errs {
+		msgs = append(msgs, err.Error())
+	}
+	return strings.Join(msgs, ", ")
+}
+
+// requiredErrors returns the error for a missing value, the validators of go-openapi/validate skip a nil value
+// so a required value which is omitted is reported here
+func requiredErrors(key, in string, required bool, value interface{}) ([]error, bool) {
+	if value != nil {
+		return nil, false
+	}
+	if required {
+		return []error{errors.Required(key, in, value)}, true
+	}
+	return nil, true
+}
+
+// checkParam validates a value for a parameter
+func checkParam(param *spec.Parameter) checker {
+	return func(value interface{}) ([]error, error) {
+		if errs, ok := requiredErrors(param.Name, param.In, param.Required, value); ok {
+			return errs, nil
+		}
+		return validate.NewParamValidator(param, strfmt.Default).Validate(value).Errors, nil
+	}
+}
+
+// checkHeader validates a value for a response header
+func checkHeader(key string, header *spec.Header) checker {
+	return func(value interface{}) ([]error, error) {
+		if errs, ok := requiredErrors(key, "header", true, value); ok {
+			return errs, nil
+		}
+		return validate.NewHeaderValidator(key, header, strfmt.Default).Validate(value).Errors, nil
+	}
+}
+
+// checkSchema validates a value for a schema, a nil value for a schema which isn't nullable is a missing value.
+// The resolved schema is the schema without references, it tells whether the schema is nullable.
+func (g *generators) checkSchema(key string, schema, resolved *spec.Schema) checker {
+	return func(value interface{}) ([]error, error) {
+		if value == nil && !resolved.Type.Contains("null") && !resolved.Nullable {
+			if nullable, ok := resolved.Extensions.GetBool("x-nullable"); !ok || !nullable {
+				errs, _ := requiredErrors(key, "body", true, value)
+				return errs, nil
+			}
+		}
+		result, err := g.validate(*schema, "", value)
+		if err != nil {
+			return nil, err
+		}
+		return result.Errors, nil
+	}
+}
@@ -0,0 +1,69 @@
+package stubs
+
+import (
+	"testing"
+
+	"github.com/go-openapi/spec"
+	"github.com/go-openapi/strfmt"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGenerator_Verify(t *testing.T) {
+	schema := new(spec.Schema).
+		Typed("object", "").
+		WithRequired("id", "name").
+		SetProperty("id", *spec.Int64Property().WithMinimum(1, false).WithMaximum(1000, false).WithMultipleOf(5)).
+		SetProperty("name", *spec.StringProperty().WithMinLength(2).WithMaxLength(10).WithPattern(`^[a-z]+$`)).
+		SetProperty("email", *spec.StrFmtProperty("email")).
+		SetProperty("active", *spec.BoolProperty()).
+		SetProperty("status", *spec.StringProperty().WithEnum("available", "pending", "sold")).
+		SetProperty("tags", *spec.ArrayProperty(spec.StringProperty()).WithMinItems(1).WithMaxItems(3).UniqueValues())
+
+	for _, mode := range append([]StubMode{Valid, Invalid}, validationModes...) {
+		gen := &Generator{Mode: mode, Verify: true}
+		for i := 0; i < 10; i++ {
+			_, err := gen.GenSchema("Pet", schema)
+			assert.NoError(t, err, mode.String())
+		}
+	}
+
+	gen := &Generator{Modes: map[string]StubMode{"/name": InvalidPattern | InvalidMaxLength, "/tags": InvalidUniqueItems}, Verify: true}
+	for i := 0; i < 10; i++ {
+		_, err := gen.GenSchema("Pet", schema)
+		assert.NoError(t, err)
+	}
+
+	param := spec.QueryParam("limit").Typed("integer", "int32").WithMaximum(100, false).WithMinimum(1, false).AsRequired()
+	for _, mode := range []StubMode{Valid, Invalid, InvalidRequired, InvalidMaximum, InvalidMinimum} {
+		gen := &Generator{Mode: mode, Verify: true}
+		_, err := gen.GenParameter("", param)
+		assert.NoError(t, err, mode.String())
+	}
+	header := spec.ResponseHeader().Typed("string", "").WithMaxLength(8)
+	_, err := (&Generator{Mode: InvalidMaxLength, Verify: true}).GenHeader("X-Request-Id", header)
+	assert.NoError(t, err)
+
+	// a value generator which doesn't produce values for the format fails verification
+	reg := NewRegistry()
+	reg.Register("contact", "string", constant("not an email"))
+	email := spec.StrFmtProperty("email")
+	email.AddExtension("x-datagen", map[string]interface{}{"name": "contact"})
+	gen = &Generator{Registry: reg, Verify: true, VerifyAttempts: 3}
+	_, err = gen.GenSchema("email", email)
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "after 3 attempts")
+	}
+	gen.Verify = false
+	res, err := gen.GenSchema("email", email)
+	if assert.NoError(t, err) {
+		assert.False(t, strfmt.IsEmail(res.(string)))
+	}
+
+	// a value which is valid for the selected validation doesn't verify
+	reg.Register("short", "string", constant("abc"))
+	short := spec.StringProperty().WithMaxLength(8)
+	short.AddExtension("x-datagen", map[string]interface{}{"name": "short"})
+	gen = &Generator{Registry: reg, Verify: true, Mode: InvalidMaxLength}
+	_, err = gen.GenSchema("code", short)
+	assert.NoError(t, err, "the mode takes precedence over the value generator")
+}